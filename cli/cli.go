@@ -16,6 +16,10 @@ package cli
 
 import (
 	"github.com/alecthomas/kong"
+
+	"github.com/getsolus/usysconf/logging"
+	"github.com/getsolus/usysconf/state"
+	"github.com/getsolus/usysconf/triggers"
 )
 
 // Version will be injected by ld flags.
@@ -23,22 +27,55 @@ var Version string = "unknown"
 
 // GlobalFlags contains the flags for all commands.
 type GlobalFlags struct {
-	Debug   bool             `short:"d" long:"debug"  help:"Run in debug mode."`
-	Chroot  bool             `short:"c" long:"chroot" help:"Specify that command is being run from a chrooted environment."`
-	Live    bool             `short:"l" long:"live"   help:"Specify that command is being run from a live medium."`
-	Version kong.VersionFlag `short:"v" long:"version"   help:"Print version and exit."`
+	Debug     bool             `short:"d" long:"debug"  help:"Run in debug mode."`
+	Chroot    bool             `short:"c" long:"chroot" xor:"chroot" help:"Specify that command is being run from a chrooted environment."`
+	NoChroot  bool             `long:"no-chroot" xor:"chroot" help:"Specify that command is not being run from a chrooted environment."`
+	Live      bool             `short:"l" long:"live"   help:"Specify that command is being run from a live medium."`
+	LogFormat string           `long:"log-format" enum:"text,json" default:"text" help:"Format of log records (text, json)."`
+	LogFile   string           `long:"log-file" type:"path" help:"Append log records to the specified file."`
+	Journal   bool             `long:"journal" help:"Send log records to the systemd journal."`
+	Syslog    bool             `long:"syslog" help:"Send log records to syslog."`
+	Version   kong.VersionFlag `short:"v" long:"version"   help:"Print version and exit."`
 }
 
 type arguments struct {
 	GlobalFlags
 
-	Run   run   `cmd:"" aliases:"r" help:"Run specified trigger(s) to update the system configuration."`
-	List  list  `cmd:"" aliases:"ls" help:"List available triggers to run (user-specific)."`
-	Graph graph `cmd:"" aliases:"g" help:"Print the dependencies for all available triggers."`
+	Run     run        `cmd:"" aliases:"r" help:"Run specified trigger(s) to update the system configuration."`
+	List    list       `cmd:"" aliases:"ls" help:"List available triggers to run (user-specific)."`
+	Graph   graph      `cmd:"" aliases:"g" help:"Print the dependencies for all available triggers."`
+	Show    show       `cmd:"" aliases:"s" help:"Show what a trigger would do right now, without running it."`
+	Why     why        `cmd:"" aliases:"w" help:"Explain why a trigger would or wouldn't run right now."`
+	History historyCmd `cmd:"" aliases:"h" help:"Show the results of previous runs."`
+	Env     env        `cmd:"" help:"Show the detected environment (chroot, live, container, virtual machine)."`
+	State   stateCmd   `cmd:"" help:"Inspect and manage the saved state."`
+
+	SandboxExec sandboxExec `cmd:"" name:"sandbox-exec" hidden:"" help:"Execute a binary inside a sandbox (internal)."`
+}
+
+// Logging gets the logging options requested by the flags.
+func (f GlobalFlags) Logging() logging.Options {
+	return logging.Options{
+		Debug:   f.Debug,
+		Format:  f.LogFormat,
+		File:    f.LogFile,
+		Journal: f.Journal,
+		Syslog:  f.Syslog,
+	}
+}
+
+// Scope gets the scope requested by the flags, without detecting the environment.
+func (f GlobalFlags) Scope() triggers.Scope {
+	return triggers.Scope{
+		Chroot: f.Chroot,
+		Debug:  f.Debug,
+		Live:   f.Live,
+	}
 }
 
 func Parse() (*kong.Context, GlobalFlags) {
 	var args arguments
 	ctx := kong.Parse(&args, kong.Vars{"version": Version})
+	state.ToolVersion = Version
 	return ctx, args.GlobalFlags
 }
@@ -0,0 +1,93 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/getsolus/usysconf/triggers"
+	"github.com/getsolus/usysconf/util"
+)
+
+type env struct{}
+
+// Run prints the detected environment and the reasons for it
+func (e env) Run(flags GlobalFlags) error {
+	detected := util.DetectEnvironment()
+	fmt.Printf("%-10s %s\n", "chroot:", yesNo(detected.Chroot, detected.ChrootErr))
+	fmt.Printf("%-10s %s\n", "live:", yesNo(detected.Live, detected.LiveErr))
+	fmt.Printf("%-10s %s\n", "container:", orNone(detected.Container))
+	fmt.Printf("%-10s %s\n", "virt:", orNone(detected.Virt))
+	if len(detected.Reasons) > 0 {
+		fmt.Println("\nReasons:")
+		for _, reason := range detected.Reasons {
+			fmt.Printf("    %s\n", reason)
+		}
+	}
+	return nil
+}
+
+// detectScope gets the scope requested by the flags, in the detected environment
+func detectScope(flags GlobalFlags) triggers.Scope {
+	detected := util.DetectEnvironment()
+	flags.Chroot, flags.Live = environment(flags, detected)
+	s := flags.Scope()
+	s.Container = detected.Container
+	s.Virt = detected.Virt
+	return s
+}
+
+// environment decides if the run is in a chroot or live medium, honouring the flags.
+// A chroot is assumed when it can't be detected, since it skips the riskier triggers.
+func environment(flags GlobalFlags, detected util.Environment) (chroot, live bool) {
+	switch {
+	case flags.Chroot || flags.NoChroot:
+		chroot = flags.Chroot
+	case detected.ChrootErr != nil:
+		slog.Warn("Failed to detect chroot, assuming chroot and continuing", "reason", detected.ChrootErr)
+		chroot = true
+	default:
+		chroot = detected.Chroot
+	}
+	switch {
+	case flags.Live:
+		live = true
+	case detected.LiveErr != nil:
+		slog.Warn("Failed to detect live session, assuming installed system", "reason", detected.LiveErr)
+	default:
+		live = detected.Live
+	}
+	return
+}
+
+// yesNo renders a detected boolean for humans
+func yesNo(b bool, err error) string {
+	if err != nil {
+		return "unknown"
+	}
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// orNone renders an empty string as "none"
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
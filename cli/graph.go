@@ -16,18 +16,63 @@ package cli
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/getsolus/usysconf/config"
 )
 
-type graph struct{}
+type graph struct {
+	Format string `short:"f" long:"format" enum:"dot,mermaid,json,text-tree" default:"dot" help:"Format of the graph (dot, mermaid, json, text-tree)."`
+	For    string `long:"for" help:"Only show this trigger, the triggers it runs after and the triggers that run after it."`
+	Order  bool   `short:"o" long:"order" help:"Print the order the triggers would run in, by level, instead of the graph."`
+}
 
-// GraphDepsRun prints the usage for the requested command
+// Run prints the dependency graph, or the order of execution, in the requested format
 func (g graph) Run(flags GlobalFlags) error {
 	tm, err := config.LoadAll()
 	if err != nil {
 		return fmt.Errorf("failed to load triggers: %w", err)
 	}
-	tm.Graph(flags.Chroot, flags.Live).Print()
+	s := detectScope(flags)
+	dg := tm.Graph(s)
+	known := make([]string, 0, len(tm))
+	for name := range tm {
+		known = append(known, name)
+	}
+	e := dg.Export(tm.Nodes(s), known)
+	if g.For != "" {
+		t, ok := tm[g.For]
+		if !ok {
+			return fmt.Errorf("trigger '%s' not found", g.For)
+		}
+		if skip, reason := t.Skip.Matches(s); skip {
+			return fmt.Errorf("trigger '%s' is left out of the graph: %s", g.For, reason)
+		}
+		names := append([]string{g.For}, dg.Dependencies(g.For)...)
+		e = e.Filter(append(names, dg.Dependents(g.For)...))
+	}
+	if g.Order {
+		var names []string
+		for _, node := range e.Nodes {
+			if !node.Missing && !node.Outside {
+				names = append(names, node.Name)
+			}
+		}
+		for i, level := range tm.Graph(s).Levels(names) {
+			fmt.Printf("%d: %s\n", i+1, strings.Join(level, ", "))
+		}
+		return nil
+	}
+	switch g.Format {
+	case "mermaid":
+		e.Mermaid(os.Stdout)
+	case "json":
+		return e.JSON(os.Stdout)
+	case "text-tree":
+		e.Tree(os.Stdout)
+	default:
+		e.Dot(os.Stdout)
+	}
 	return nil
 }
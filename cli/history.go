@@ -0,0 +1,121 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/getsolus/usysconf/history"
+)
+
+type historyCmd struct {
+	Trigger string `short:"t" long:"trigger" help:"Only show results for the named trigger."`
+	Status  string `short:"s" long:"status"  help:"Only show results with this status (skipped, success, failure)."`
+	Since   string `long:"since" help:"Only show runs started after this time (RFC 3339, date, or duration ago)."`
+	Until   string `long:"until" help:"Only show runs started before this time (RFC 3339, date, or duration ago)."`
+	JSON    bool   `short:"j" long:"json" help:"Print the history as JSON."`
+
+	PruneAge  time.Duration `long:"prune-age"  help:"Remove runs older than this duration, instead of printing."`
+	PruneSize int64         `long:"prune-size" help:"Remove the oldest runs until the history fits in this many bytes, instead of printing."`
+}
+
+func (h historyCmd) Run(flags GlobalFlags) error {
+	if h.PruneAge > 0 || h.PruneSize > 0 {
+		removed, err := history.Prune(h.PruneAge, h.PruneSize)
+		if err != nil {
+			return fmt.Errorf("failed to prune history: %w", err)
+		}
+		slog.Info("Pruned history", "removed", removed)
+		return nil
+	}
+	var since, until time.Time
+	var err error
+	if since, err = parseTime(h.Since); err != nil {
+		return err
+	}
+	if until, err = parseTime(h.Until); err != nil {
+		return err
+	}
+	records, err := history.Load()
+	if err != nil {
+		return fmt.Errorf("failed to read history: %w", err)
+	}
+	matched := []history.Record{}
+	for _, r := range records {
+		if !since.IsZero() && r.Start.Before(since) {
+			continue
+		}
+		if !until.IsZero() && r.Start.After(until) {
+			continue
+		}
+		var entries []history.Entry
+		for _, e := range r.Triggers {
+			if h.Trigger != "" && e.Name != h.Trigger {
+				continue
+			}
+			if h.Status != "" && e.Status != h.Status {
+				continue
+			}
+			entries = append(entries, e)
+		}
+		if len(entries) == 0 && (h.Trigger != "" || h.Status != "") {
+			continue
+		}
+		r.Triggers = entries
+		matched = append(matched, r)
+	}
+	if h.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(matched)
+	}
+	for _, r := range matched {
+		fmt.Printf("%s  %s  %s  (%s, %d changed)\n", r.ID, r.Start.Local().Format(time.DateTime),
+			strings.Join(r.Command, " "), r.Duration.Round(time.Millisecond), r.Changed)
+		for _, e := range r.Triggers {
+			fmt.Printf("    %-24s %-8s %10s", e.Name, e.Status, e.Duration.Round(time.Millisecond))
+			if e.Message != "" {
+				fmt.Printf("  %s", e.Message)
+			}
+			fmt.Println()
+		}
+	}
+	return nil
+}
+
+// parseTime reads a timestamp, a date, or a duration relative to now
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateTime, value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognised time '%s'", value)
+}
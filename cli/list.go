@@ -21,7 +21,9 @@ import (
 	"github.com/getsolus/usysconf/config"
 )
 
-type list struct{}
+type list struct {
+	Tags []string `short:"T" name:"tag" help:"Only list the triggers with this tag."`
+}
 
 func (l list) Run(flags GlobalFlags) error {
 	tm, err := config.LoadAll()
@@ -29,6 +31,9 @@ func (l list) Run(flags GlobalFlags) error {
 		return fmt.Errorf("failed to load triggers: %w", err)
 	}
 	slog.Info("Available triggers:")
-	tm.Print(flags.Chroot, flags.Live)
+	if len(l.Tags) > 0 {
+		tm = tm.Tagged(l.Tags)
+	}
+	tm.Print(detectScope(flags))
 	return nil
 }
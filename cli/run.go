@@ -15,54 +15,155 @@
 package cli
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
 
 	"github.com/getsolus/usysconf/config"
+	"github.com/getsolus/usysconf/history"
+	"github.com/getsolus/usysconf/logging"
+	"github.com/getsolus/usysconf/state"
 	"github.com/getsolus/usysconf/triggers"
-	"github.com/getsolus/usysconf/util"
+	"github.com/getsolus/usysconf/ui"
 )
 
 type run struct {
-	Force  bool `short:"f" long:"force"   help:"Force run the configuration regardless if it should be skipped."`
-	DryRun bool `short:"n" long:"dry-run" help:"Test the configuration files without executing the specified binaries and arguments."`
+	Force      bool          `short:"f" long:"force"   help:"Force run the configuration regardless if it should be skipped."`
+	DryRun     bool          `short:"n" long:"dry-run" help:"Test the configuration files without executing the specified binaries and arguments."`
+	Stream     bool          `short:"s" long:"stream"   help:"Show the output of the executed binaries as they run."`
+	Progress   bool          `short:"p" long:"progress"   help:"Show the progress of each trigger and a summary at the end."`
+	Background bool          `short:"b" long:"background" help:"Run binaries at the lowest CPU and I/O priority unless they specify their own."`
+	Timeout    time.Duration `short:"t" long:"timeout"    help:"Stop running binaries and triggers after this long."`
+	JSON       bool          `short:"j" long:"json"       help:"Print the plan of a dry-run as JSON."`
+	Since      string        `long:"since" help:"Treat checked paths modified since this time, or state snapshot, as changed."`
 
-	Triggers []string `arg:"" help:"Names of the triggers to run." optional:""`
+	Tags    []string `short:"T" name:"tag"     help:"Run the triggers with this tag."`
+	Exclude []string `short:"x" long:"exclude" help:"Don't run the triggers matching this name, glob or @tag."`
+
+	Triggers []string `arg:"" help:"Names, globs or @tags of the triggers to run." optional:""`
 }
 
 func (r run) Run(flags GlobalFlags) error {
+	if r.JSON && !r.DryRun {
+		return errors.New("--json can only be used with --dry-run")
+	}
 	if os.Geteuid() != 0 {
 		return errors.New("you must have root privileges to run triggers")
 	}
-	if util.IsChroot() {
-		flags.Chroot = true
-	}
-	if util.IsLive() {
-		flags.Live = true
+	if err := logging.AttachRunLog(); err != nil {
+		slog.Warn("Failed to open run log", "reason", err)
 	}
 	// Load Triggers.
 	tm, err := config.LoadAll()
 	if err != nil {
 		return fmt.Errorf("failed to load triggers: %w", err)
 	}
-	// If the names flag is not present, retrieve the names of the
-	// configurations in the system and usr directories.
-	n := r.Triggers
-	if len(n) == 0 {
-		for k := range tm {
-			n = append(n, k)
-		}
+	// Select the triggers to run, or all of them if none are requested.
+	n := tm.Select(r.Triggers, r.Tags, r.Exclude)
+	// Stop cleanly when interrupted or out of time.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
 	}
 	// Establish scope of operations.
-	s := triggers.Scope{
-		Chroot: flags.Chroot,
-		Debug:  flags.Debug,
-		DryRun: r.DryRun,
-		Forced: r.Force,
-		Live:   flags.Live,
+	start := time.Now()
+	s := detectScope(flags)
+	s.Context = ctx
+	s.ID = history.NewID(start)
+	s.DryRun = r.DryRun
+	s.NoExec = r.DryRun
+	s.Forced = r.Force
+	s.Stream = r.Stream
+	s.Background = r.Background
+	if r.DryRun {
+		s.Plan = &triggers.Plan{}
+	}
+	if r.Since != "" {
+		if err = since(&s, r.Since); err != nil {
+			return err
+		}
+	}
+	// Set up progress reporting.
+	var rend ui.Renderer
+	if r.Progress {
+		rend = ui.New(os.Stdout)
+		s.Reporter = rend
+		triggers.StreamOutput = rend
+		if err := logging.SetConsole(rend, ui.IsTerminal(os.Stdout)); err != nil {
+			return err
+		}
 	}
 	// Run triggers.
-	tm.Run(s, n)
+	results := tm.Run(s, n)
+	if rend != nil {
+		rend.Close(results)
+		if err := logging.SetConsole(os.Stderr, false); err != nil {
+			return err
+		}
+	}
+	if r.DryRun {
+		return printPlan(s.Plan, r.JSON)
+	}
+	record(start, results)
 	return nil
 }
+
+// since compares a run against a state snapshot, or a point in time, instead of the saved state
+func since(s *triggers.Scope, value string) error {
+	snapshots, err := state.Snapshots()
+	if err != nil {
+		return fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	if slices.Contains(snapshots, value) {
+		if s.Baseline, err = state.LoadSnapshot(value); err != nil {
+			return fmt.Errorf("failed to read snapshot: %w", err)
+		}
+		return nil
+	}
+	if s.Since, err = parseTime(value); err != nil {
+		return fmt.Errorf("--since must be a snapshot or a time: %w", err)
+	}
+	return nil
+}
+
+// printPlan prints the plan of a dry-run, for humans or as JSON
+func printPlan(p *triggers.Plan, asJSON bool) error {
+	if !asJSON {
+		fmt.Println()
+		p.Print(os.Stdout)
+		return nil
+	}
+	return writeJSON(os.Stdout, p)
+}
+
+// record appends the results of a run to the history
+func record(start time.Time, results []triggers.Result) {
+	rec := history.Record{
+		ID:       history.NewID(start),
+		Command:  os.Args,
+		Start:    start,
+		Duration: time.Since(start),
+	}
+	for _, res := range results {
+		rec.Changed += res.Changed
+		rec.Triggers = append(rec.Triggers, history.Entry{
+			Name:     res.Name,
+			Status:   res.Status.String(),
+			Duration: res.Duration,
+			Changed:  res.Changed,
+			Message:  res.Message,
+		})
+	}
+	if err := history.Append(rec); err != nil {
+		slog.Warn("Failed to record run history", "reason", err)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/getsolus/usysconf/config"
+	"github.com/getsolus/usysconf/state"
+)
+
+type show struct {
+	Force bool `short:"f" long:"force" help:"Show the plan as if the run was forced."`
+
+	Trigger string `arg:"" help:"Name of the trigger to show."`
+}
+
+// Run prints what a trigger would do right now, without changing anything
+func (sh show) Run(flags GlobalFlags) error {
+	tm, err := config.LoadAll()
+	if err != nil {
+		return fmt.Errorf("failed to load triggers: %w", err)
+	}
+	t, ok := tm[sh.Trigger]
+	if !ok {
+		return fmt.Errorf("trigger '%s' not found", sh.Trigger)
+	}
+	saved, err := state.Load()
+	if err != nil {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	s := detectScope(flags)
+	s.Forced = sh.Force
+	s.NoExec = true
+	// Definition
+	fmt.Printf("Trigger: %s\n", t.Name)
+	fmt.Printf("Source:  %s\n", t.Path)
+	if t.Owner != "" {
+		fmt.Printf("Owner:   %s\n", t.Owner)
+	}
+	var buf bytes.Buffer
+	if err = toml.NewEncoder(&buf).Encode(t); err != nil {
+		return fmt.Errorf("failed to encode trigger: %w", err)
+	}
+	section("Configuration")
+	for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+		if line == "" {
+			fmt.Println()
+			continue
+		}
+		fmt.Printf("    %s\n", line)
+	}
+	// Checks and skips
+	section("Checks")
+	if t.Check == nil {
+		fmt.Println("    none")
+	} else {
+		for _, path := range t.Check.Paths {
+			matches, err := state.Scan([]string{path})
+			if err != nil {
+				fmt.Printf("    %s: %s\n", path, err)
+				continue
+			}
+			fmt.Printf("    %s: %d matches\n", path, len(matches))
+		}
+	}
+	e, err := t.Evaluate(s, saved)
+	if err != nil {
+		return fmt.Errorf("failed to evaluate trigger: %w", err)
+	}
+	section("Decision")
+	if e.Skip {
+		fmt.Printf("    skip: %s\n", e.Reason)
+	} else {
+		fmt.Printf("    run: %s\n", e.Reason)
+	}
+	// Actions
+	if len(t.Removals) > 0 {
+		section("Removals")
+		for _, remove := range t.Removals {
+			paths, err := remove.Targets()
+			if err != nil {
+				fmt.Printf("    %v: %s\n", remove.Paths, err)
+				continue
+			}
+			for _, path := range paths {
+				fmt.Printf("    %s\n", path)
+			}
+		}
+	}
+	bins, outputs, unmet := t.Expand(s)
+	if len(bins) > 0 {
+		section("Commands")
+		for i, b := range bins {
+			if unmet[i] {
+				fmt.Printf("    [%s] skipped: %s\n", outputs[i].Name, outputs[i].Message)
+				continue
+			}
+			label := outputs[i].Name
+			if outputs[i].SubTask != "" {
+				label += ": " + outputs[i].SubTask
+			}
+			fmt.Printf("    [%s] %s\n", label, strings.Join(append([]string{b.Bin}, b.Args...), " "))
+			if b.Condition != nil {
+				v, reason := b.Condition.Evaluate(s, t.Owner)
+				fmt.Printf("        condition %s: %s\n", v, reason)
+			}
+		}
+	}
+	// Dependencies
+	section("Runs after")
+	after := tm.Graph(s).Dependencies(t.Name)
+	if len(after) == 0 {
+		fmt.Println("    none")
+	}
+	for _, name := range after {
+		fmt.Printf("    %s\n", name)
+	}
+	return nil
+}
+
+// section prints the heading of a section
+func section(name string) {
+	fmt.Printf("\n%s:\n", name)
+}
@@ -0,0 +1,317 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/getsolus/usysconf/config"
+	"github.com/getsolus/usysconf/state"
+)
+
+type stateCmd struct {
+	Show       stateShow       `cmd:"" help:"Show the saved modification times of checked paths."`
+	Forget     stateForget     `cmd:"" help:"Forget the state of a trigger, so that it runs next time."`
+	ForgetPath stateForgetPath `cmd:"" name:"forget-path" help:"Forget the state of the paths matching a glob."`
+	Verify     stateVerify     `cmd:"" help:"Check the state for corruption and entries which are no longer needed."`
+	Compact    stateCompact    `cmd:"" help:"Remove entries which are no longer needed from the state."`
+	Export     stateExport     `cmd:"" help:"Write the state as JSON."`
+	Import     stateImport     `cmd:"" help:"Replace the state with one exported as JSON."`
+	Snapshot   stateSnapshot   `cmd:"" help:"Save a named copy of the state."`
+	Restore    stateRestore    `cmd:"" help:"Replace the state with a named copy."`
+	Snapshots  stateSnapshots  `cmd:"" help:"List the saved copies of the state."`
+}
+
+type stateShow struct {
+	Trigger string `short:"t" long:"trigger" help:"Only show the paths checked by this trigger."`
+	JSON    bool   `short:"j" long:"json" help:"Print the state as JSON."`
+}
+
+// Run prints the saved state, or part of it
+func (ss stateShow) Run(flags GlobalFlags) error {
+	st, err := state.Load()
+	if err != nil {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	shown := st.Paths
+	if ss.Trigger != "" {
+		if shown, err = triggerState(st.Paths, ss.Trigger); err != nil {
+			return err
+		}
+	}
+	if ss.JSON {
+		return writeJSON(os.Stdout, shown)
+	}
+	paths := shown.Strings()
+	sort.Strings(paths)
+	for _, path := range paths {
+		fmt.Printf("%s  %s\n", stamp(shown[path]), path)
+	}
+	return nil
+}
+
+type stateForget struct {
+	Trigger string `arg:"" help:"Name of the trigger to forget."`
+}
+
+// Run removes the paths checked by a trigger from the state, along with everything
+// recorded about its previous runs
+func (sf stateForget) Run(flags GlobalFlags) error {
+	st, err := state.Load()
+	if err != nil {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	forgotten, err := triggerState(st.Paths, sf.Trigger)
+	if err != nil {
+		return err
+	}
+	delete(st.Once, sf.Trigger)
+	delete(st.Definitions, sf.Trigger)
+	return forget(st, forgotten)
+}
+
+type stateForgetPath struct {
+	Glob string `arg:"" help:"Glob of the paths to forget."`
+}
+
+// Run removes the paths matching a glob from the state
+func (sf stateForgetPath) Run(flags GlobalFlags) error {
+	st, err := state.Load()
+	if err != nil {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	forgotten, err := st.Paths.Glob(sf.Glob)
+	if err != nil {
+		return fmt.Errorf("invalid glob '%s': %w", sf.Glob, err)
+	}
+	return forget(st, forgotten)
+}
+
+type stateVerify struct{}
+
+// Run checks that the state can be read, and reports entries which are no longer needed
+func (sv stateVerify) Run(flags GlobalFlags) error {
+	st, err := state.Load()
+	if err != nil {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	dangling, untracked, err := unneeded(st.Paths)
+	if err != nil {
+		return err
+	}
+	writer := "an older usysconf"
+	if st.ToolVersion != "" {
+		writer = "usysconf " + st.ToolVersion
+	}
+	fmt.Printf("format version %d, written by %s\n", st.Version, writer)
+	if st.Version < state.Version {
+		fmt.Printf("the next save will upgrade it to version %d\n", state.Version)
+	}
+	fmt.Printf("%d entries\n", len(st.Paths))
+	for _, problem := range []struct {
+		name    string
+		entries state.Map
+	}{
+		{"paths which no longer exist", dangling},
+		{"paths not checked by any trigger", untracked},
+	} {
+		fmt.Printf("%d %s\n", len(problem.entries), problem.name)
+		paths := problem.entries.Strings()
+		sort.Strings(paths)
+		for _, path := range paths {
+			fmt.Printf("    %s\n", path)
+		}
+	}
+	if len(dangling) > 0 || len(untracked) > 0 {
+		return errors.New("state has entries which are no longer needed, use 'state compact' to remove them")
+	}
+	return nil
+}
+
+type stateCompact struct{}
+
+// Run removes entries which are no longer needed from the state
+func (sc stateCompact) Run(flags GlobalFlags) error {
+	st, err := state.Load()
+	if err != nil {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	dangling, untracked, err := unneeded(st.Paths)
+	if err != nil {
+		return err
+	}
+	dangling.Merge(untracked)
+	return forget(st, dangling)
+}
+
+type stateExport struct {
+	File string `arg:"" optional:"" type:"path" help:"File to write to, the standard output by default."`
+}
+
+// Run writes the state as JSON
+func (se stateExport) Run(flags GlobalFlags) error {
+	st, err := state.Load()
+	if err != nil {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	if se.File == "" {
+		return writeJSON(os.Stdout, st)
+	}
+	f, err := os.Create(se.File)
+	if err != nil {
+		return err
+	}
+	if err = writeJSON(f, st); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+type stateImport struct {
+	File string `arg:"" type:"existingfile" help:"File to read the exported state from."`
+}
+
+// Run replaces the state with one exported as JSON
+func (si stateImport) Run(flags GlobalFlags) error {
+	raw, err := os.ReadFile(si.File)
+	if err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(raw, &fields); err != nil {
+		return fmt.Errorf("failed to read %s: %w", si.File, err)
+	}
+	st := state.New()
+	if _, ok := fields["paths"]; ok {
+		err = json.Unmarshal(raw, st)
+	} else {
+		// Exports used to be a bare map of paths
+		err = json.Unmarshal(raw, &st.Paths)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", si.File, err)
+	}
+	if st.Paths == nil {
+		st.Paths = make(state.Map)
+	}
+	if err = st.Save(); err != nil {
+		return fmt.Errorf("failed to save state file: %w", err)
+	}
+	slog.Info("Imported state", "entries", len(st.Paths))
+	return nil
+}
+
+// triggerState finds the part of the state checked by a trigger
+func triggerState(st state.Map, name string) (state.Map, error) {
+	tm, err := config.LoadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load triggers: %w", err)
+	}
+	t, ok := tm[name]
+	if !ok {
+		return nil, fmt.Errorf("trigger '%s' not found", name)
+	}
+	if t.Check == nil {
+		return make(state.Map), nil
+	}
+	return st.Within(t.Check.Paths), nil
+}
+
+// unneeded finds the entries of the state for paths which no longer exist, and for
+// paths which no trigger checks
+func unneeded(st state.Map) (dangling, untracked state.Map, err error) {
+	tm, err := config.LoadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load triggers: %w", err)
+	}
+	untracked = st.Copy()
+	for _, t := range tm {
+		if t.Check != nil {
+			untracked.Delete(st.Within(t.Check.Paths))
+		}
+	}
+	dangling = st.Dangling()
+	untracked.Delete(dangling)
+	return
+}
+
+// forget removes entries from the state, and saves it
+func forget(st *state.State, forgotten state.Map) error {
+	st.Paths.Delete(forgotten)
+	if err := st.Save(); err != nil {
+		return fmt.Errorf("failed to save state file: %w", err)
+	}
+	slog.Info("Forgot paths", "count", len(forgotten))
+	return nil
+}
+
+// writeJSON writes a value as indented JSON
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+type stateSnapshot struct {
+	Name string `arg:"" optional:"" help:"Name of the snapshot, the current time by default."`
+}
+
+// Run saves a named copy of the state
+func (ss stateSnapshot) Run(flags GlobalFlags) error {
+	name := ss.Name
+	if name == "" {
+		name = time.Now().UTC().Format("20060102T150405Z")
+	}
+	if err := state.Snapshot(name); err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	slog.Info("Saved snapshot", "name", name)
+	return nil
+}
+
+type stateRestore struct {
+	Name string `arg:"" help:"Name of the snapshot to restore."`
+}
+
+// Run replaces the state with a named copy
+func (sr stateRestore) Run(flags GlobalFlags) error {
+	if err := state.Restore(sr.Name); err != nil {
+		return fmt.Errorf("failed to restore snapshot: %w", err)
+	}
+	slog.Info("Restored snapshot", "name", sr.Name)
+	return nil
+}
+
+type stateSnapshots struct{}
+
+// Run lists the saved copies of the state
+func (ss stateSnapshots) Run(flags GlobalFlags) error {
+	names, err := state.Snapshots()
+	if err != nil {
+		return fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	for _, name := range names {
+		fmt.Println(name)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/getsolus/usysconf/config"
+	"github.com/getsolus/usysconf/state"
+	"github.com/getsolus/usysconf/triggers"
+)
+
+// whyLimit is the number of changed paths shown, unless all of them are requested
+const whyLimit = 20
+
+type why struct {
+	Force bool `short:"f" long:"force" help:"Explain the decision as if the run was forced."`
+	All   bool `short:"a" long:"all" help:"List every changed path instead of the first few."`
+
+	Trigger string `arg:"" help:"Name of the trigger to explain."`
+}
+
+// Run explains why a trigger would or wouldn't run right now
+func (w why) Run(flags GlobalFlags) error {
+	tm, err := config.LoadAll()
+	if err != nil {
+		return fmt.Errorf("failed to load triggers: %w", err)
+	}
+	t, ok := tm[w.Trigger]
+	if !ok {
+		return fmt.Errorf("trigger '%s' not found", w.Trigger)
+	}
+	saved, err := state.Load()
+	if err != nil {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	s := detectScope(flags)
+	s.Forced = w.Force
+	s.NoExec = true
+	e, err := t.Evaluate(s, saved)
+	if err != nil {
+		return fmt.Errorf("failed to evaluate trigger: %w", err)
+	}
+	// Decision, and whether forcing changes it
+	fmt.Printf("Trigger:  %s\n", t.Name)
+	fmt.Printf("Decision: %s\n", decision(e))
+	s.Forced = !w.Force
+	other := e
+	t.Decide(s, saved, &other)
+	flag := "Forcing the run"
+	if w.Force {
+		flag = "Not forcing the run"
+	}
+	if other.Skip == e.Skip {
+		fmt.Printf("%s would not change this\n", flag)
+	} else {
+		fmt.Printf("%s would change this to: %s\n", flag, decision(other))
+	}
+	// Definition, checks and changes
+	section("Definition")
+	switch _, recorded := saved.Definitions[t.Name]; {
+	case !recorded:
+		fmt.Println("    not recorded yet, so it will be recorded on the next run")
+	case e.Redefined:
+		fmt.Println("    changed since the last run")
+	default:
+		fmt.Println("    unchanged since the last run")
+	}
+	section("Checks")
+	if t.Check == nil {
+		fmt.Println("    none, so the trigger never runs on its own")
+	} else {
+		for _, path := range t.Check.Paths {
+			fmt.Printf("    %s: %d matches\n", path, len(e.Check.Within([]string{path})))
+		}
+	}
+	section("Changes since the last run")
+	var lines []string
+	for path, mod := range e.Diff {
+		if was, ok := saved.Paths[path]; ok {
+			lines = append(lines, fmt.Sprintf("%s  newer    %s (was %s)", path, stamp(mod), stamp(was)))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s  new      %s", path, stamp(mod)))
+		}
+	}
+	for path, was := range e.Deleted {
+		lines = append(lines, fmt.Sprintf("%s  deleted  (was %s, not counted as a change)", path, stamp(was)))
+	}
+	sort.Strings(lines)
+	if len(lines) == 0 {
+		fmt.Println("    none")
+	}
+	for i, line := range lines {
+		if i == whyLimit && !w.All {
+			fmt.Printf("    ... and %d more, use --all to list them\n", len(lines)-i)
+			break
+		}
+		fmt.Printf("    %s\n", line)
+	}
+	// Conditions and skip rules
+	if e.Condition != nil {
+		section("Condition")
+		fmt.Printf("    %s: %s\n", e.Condition.Verdict, e.Condition.Reason)
+	}
+	section("Skip rules")
+	if t.Skip == nil {
+		fmt.Println("    none")
+	} else {
+		for _, rule := range []struct {
+			set     bool
+			name    string
+			applies bool
+		}{
+			{t.Skip.Chroot, "chroot", s.Chroot},
+			{t.Skip.Live, "live", s.Live},
+			{t.Skip.Container, "container", s.Container != ""},
+			{t.Skip.Virt, "virt", s.Virt != ""},
+		} {
+			if rule.set {
+				fmt.Printf("    %s: %s\n", rule.name, applies(rule.applies))
+			}
+		}
+		for _, path := range t.Skip.Paths {
+			found := len(e.Check.Search([]string{path}))
+			fmt.Printf("    path %s: %s\n", path, applies(found > 0))
+		}
+	}
+	// Dependencies
+	section("Dependencies")
+	g := tm.Graph(s)
+	after, before := g.Dependencies(t.Name), g.Dependents(t.Name)
+	fmt.Printf("    runs after:  %s\n", orNone(strings.Join(after, ", ")))
+	fmt.Printf("    runs before: %s\n", orNone(strings.Join(before, ", ")))
+	fmt.Println("    (dependencies only order the triggers in a run, they never add triggers to it)")
+	return nil
+}
+
+// decision describes an Evaluation in a few words
+func decision(e triggers.Evaluation) string {
+	if e.Skip {
+		return "skip, " + e.Reason
+	}
+	return "run, " + e.Reason
+}
+
+// applies describes whether a skip rule applies
+func applies(b bool) string {
+	if b {
+		return "applies"
+	}
+	return "does not apply"
+}
+
+// stamp formats a modification time
+func stamp(t time.Time) string {
+	return t.Local().Format(time.DateTime)
+}
@@ -25,18 +25,29 @@ import (
 	"github.com/getsolus/usysconf/triggers"
 )
 
-// Load reads in all of the trigger files in a directory.
-func Load(path string) (triggers.Map, error) {
+// Load reads in all of the trigger files in a directory. Files and directories, including
+// the directories above them, must be owned by root or the owner, if any, and must not be
+// writable by anyone else. Triggers belonging to an owner other than root will run as that user.
+func Load(path string, owner *user.User) (triggers.Map, error) {
 	logger := slog.With("path", path)
 
-	entries, err := os.ReadDir(path)
+	dir, err := openDir(path, owner)
 	if err != nil {
 		if os.IsNotExist(err) {
 			logger.Debug("Directory not found")
 			return nil, nil
 		}
+		logger.Warn("Ignoring untrusted directory", "reason", err)
+		return nil, nil
+	}
+	defer dir.Close()
+	entries, err := dir.ReadDir(-1)
+	if err != nil {
 		return nil, fmt.Errorf("failed to read triggers: %w", err)
 	}
+	if owner != nil && owner.Uid == "0" {
+		owner = nil
+	}
 	tm := make(triggers.Map, len(entries))
 	logger.Debug("Scanning directory")
 	for _, entry := range entries {
@@ -52,14 +63,27 @@ func Load(path string) (triggers.Map, error) {
 			Path: filepath.Clean(filepath.Join(path, name)),
 		}
 		logger.Debug("Trigger found", "name", t.Name)
-		err = t.Load(t.Path)
+		f, err := openTrigger(dir, name, owner)
 		if err != nil {
-			return nil, err
+			logger.Warn("Ignoring untrusted trigger", "name", t.Name, "reason", err)
+			continue
+		}
+		err = t.Decode(f)
+		_ = f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read config file located at %s due to %s", t.Path, err)
 		}
 		err = t.Validate()
 		if err != nil {
 			return nil, fmt.Errorf("failed to read %s from %s: %w", name, path, err)
 		}
+		if owner != nil {
+			t.Owner = owner.Username
+			if err = t.ValidateOwner(); err != nil {
+				logger.Warn("Ignoring user trigger", "name", t.Name, "reason", err)
+				continue
+			}
+		}
 		tm[t.Name] = t
 	}
 	if len(tm) == 0 {
@@ -72,9 +96,11 @@ func Load(path string) (triggers.Map, error) {
 // configuration file that has the passed name parameter, without the extension
 // and will create a config with the specified valus.
 func LoadAll() (triggers.Map, error) {
-	paths := []string{SysDir, UsrDir}
-	if p, err := os.UserHomeDir(); err != nil {
-		paths = append(paths, p)
+	sources := []source{{path: SysDir}, {path: UsrDir}}
+	if u, err := user.Current(); err != nil {
+		slog.Warn("Failed to lookup current user", "reason", err)
+	} else {
+		sources = append(sources, source{path: filepath.Join(u.HomeDir, ".config", "usysconf.d"), owner: u})
 	}
 	if os.Getuid() == 0 {
 		uname := os.Getenv("SUDO_USER")
@@ -83,14 +109,14 @@ func LoadAll() (triggers.Map, error) {
 			if err != nil {
 				slog.Warn("Failed to lookup underlying user", "name", uname, "reason", err)
 			} else {
-				paths = append(paths, filepath.Join(u.HomeDir, ".config", "usysconf.d"))
+				sources = append(sources, source{path: filepath.Join(u.HomeDir, ".config", "usysconf.d"), owner: u})
 			}
 		}
 	}
 
 	tm := make(triggers.Map)
-	for _, path := range paths {
-		trig, err := Load(path)
+	for _, src := range sources {
+		trig, err := Load(src.path, src.owner)
 		if err != nil {
 			return nil, err
 		}
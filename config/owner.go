@@ -0,0 +1,109 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// source is a directory of triggers and the user that is allowed to own it
+type source struct {
+	path  string
+	owner *user.User
+}
+
+// openDir opens a directory of triggers once its symlinks are resolved, making sure that it
+// and every directory above it are trusted, so that none of them can be swapped out
+func openDir(path string, owner *user.User) (*os.File, error) {
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, err
+	}
+	for parent := filepath.Dir(real); ; parent = filepath.Dir(parent) {
+		info, err := os.Lstat(parent)
+		if err != nil {
+			return nil, err
+		}
+		if err = checkOwner(parent, info, owner, true); err != nil {
+			return nil, err
+		}
+		if parent == filepath.Dir(parent) {
+			break
+		}
+	}
+	f, err := os.OpenFile(real, os.O_RDONLY|syscall.O_NOFOLLOW|syscall.O_DIRECTORY, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkFile(f, owner); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// openTrigger opens a trigger file inside of a trusted directory, without following
+// symlinks, and makes sure that the file itself is trusted
+func openTrigger(dir *os.File, name string, owner *user.User) (*os.File, error) {
+	fd, err := syscall.Openat(int(dir.Fd()), name, syscall.O_RDONLY|syscall.O_NOFOLLOW|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: filepath.Join(dir.Name(), name), Err: err}
+	}
+	f := os.NewFile(uintptr(fd), filepath.Join(dir.Name(), name))
+	if err = checkFile(f, owner); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// checkFile checks the ownership of an opened file
+func checkFile(f *os.File, owner *user.User) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() && !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", f.Name())
+	}
+	return checkOwner(f.Name(), info, owner, false)
+}
+
+// checkOwner makes sure that a path is owned by root or the expected owner, and that nobody
+// else can write to it. Parent directories may be writable by others when they are sticky,
+// since then nobody else can replace what is inside of them.
+func checkOwner(path string, info fs.FileInfo, owner *user.User, parent bool) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("unable to check ownership of %s", path)
+	}
+	uid := strconv.FormatUint(uint64(stat.Uid), 10)
+	if uid != "0" && (owner == nil || uid != owner.Uid) {
+		return fmt.Errorf("%s is owned by an untrusted user (uid %s)", path, uid)
+	}
+	if parent && info.Mode()&fs.ModeSticky != 0 {
+		return nil
+	}
+	if info.Mode().Perm()&0022 != 0 {
+		return fmt.Errorf("%s is writable by group or others (mode %04o)", path, info.Mode().Perm())
+	}
+	return nil
+}
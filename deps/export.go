@@ -0,0 +1,252 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deps
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Kinds of dependency between triggers
+const (
+	// After - The trigger runs after an existing trigger
+	After = "after"
+	// Missing - The trigger runs after a trigger which does not exist
+	Missing = "missing"
+	// Outside - The trigger runs after a trigger left out of the graph, because it is
+	// skipped in this environment or wasn't selected
+	Outside = "outside"
+)
+
+// Node describes a single trigger in an Export
+type Node struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Skip        []string `json:"skip,omitempty"`
+	Missing     bool     `json:"missing,omitempty"`
+	Outside     bool     `json:"outside,omitempty"`
+}
+
+// Edge is a dependency of one trigger on another
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"`
+}
+
+// Export is a Graph with the details of every trigger, including those without dependencies
+type Export struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+// Export combines the graph with the details of its triggers, keyed by name. Triggers
+// which exist, listed in known, but have no details are left out of the graph, and are
+// only missing when they aren't known either.
+func (g Graph) Export(nodes map[string]Node, known []string) (e Export) {
+	exists := make(map[string]bool, len(known))
+	for _, name := range known {
+		exists[name] = true
+	}
+	extra := make(map[string]Node)
+	for name, deps := range g {
+		for _, dep := range deps {
+			kind := After
+			if _, ok := nodes[dep]; !ok {
+				kind = Missing
+				if exists[dep] {
+					kind = Outside
+				}
+				extra[dep] = Node{Name: dep, Missing: kind == Missing, Outside: kind == Outside}
+			}
+			e.Edges = append(e.Edges, Edge{From: name, To: dep, Kind: kind})
+		}
+	}
+	for _, node := range nodes {
+		e.Nodes = append(e.Nodes, node)
+	}
+	for _, node := range extra {
+		e.Nodes = append(e.Nodes, node)
+	}
+	e.sort()
+	return
+}
+
+// sort orders the nodes and edges by name, so the output is stable
+func (e Export) sort() {
+	sort.Slice(e.Nodes, func(i, j int) bool {
+		return e.Nodes[i].Name < e.Nodes[j].Name
+	})
+	sort.Slice(e.Edges, func(i, j int) bool {
+		if e.Edges[i].From != e.Edges[j].From {
+			return e.Edges[i].From < e.Edges[j].From
+		}
+		return e.Edges[i].To < e.Edges[j].To
+	})
+}
+
+// Filter keeps only the named triggers and their dependencies. Dependencies on triggers
+// which weren't named are kept as left out of the graph.
+func (e Export) Filter(names []string) (f Export) {
+	keep := make(map[string]bool, len(names))
+	for _, name := range names {
+		keep[name] = true
+	}
+	byName := make(map[string]Node, len(e.Nodes))
+	for _, node := range e.Nodes {
+		byName[node.Name] = node
+		if keep[node.Name] {
+			f.Nodes = append(f.Nodes, node)
+		}
+	}
+	extra := make(map[string]bool)
+	for _, edge := range e.Edges {
+		if !keep[edge.From] {
+			continue
+		}
+		if !keep[edge.To] && !extra[edge.To] {
+			extra[edge.To] = true
+			node := byName[edge.To]
+			if !node.Missing {
+				node = Node{Name: edge.To, Outside: true}
+			}
+			f.Nodes = append(f.Nodes, node)
+		}
+		if !keep[edge.To] && edge.Kind == After {
+			edge.Kind = Outside
+		}
+		f.Edges = append(f.Edges, edge)
+	}
+	f.sort()
+	return
+}
+
+// label describes a node over one or more lines
+func (n Node) label() []string {
+	lines := []string{n.Name}
+	switch {
+	case n.Missing:
+		lines = append(lines, "(missing)")
+	case n.Outside:
+		lines = append(lines, "(left out)")
+	}
+	if n.Description != "" {
+		lines = append(lines, n.Description)
+	}
+	if len(n.Skip) > 0 {
+		lines = append(lines, "skip: "+strings.Join(n.Skip, ", "))
+	}
+	return lines
+}
+
+// Dot renders the graph in the "dot" format of Graphviz
+func (e Export) Dot(w io.Writer) {
+	fmt.Fprintln(w, "digraph {")
+	for _, node := range e.Nodes {
+		label := strings.ReplaceAll(strings.Join(node.label(), "\n"), `"`, `\"`)
+		label = strings.ReplaceAll(label, "\n", `\n`)
+		style := ""
+		switch {
+		case node.Missing:
+			style = ", style=dashed, color=red"
+		case node.Outside:
+			style = ", style=dotted, color=grey, fontcolor=grey"
+		}
+		fmt.Fprintf(w, "\t\"%s\" [label=\"%s\"%s];\n", node.Name, label, style)
+	}
+	for _, edge := range e.Edges {
+		style := ""
+		switch edge.Kind {
+		case Missing:
+			style = " [style=dashed, color=red]"
+		case Outside:
+			style = " [style=dotted, color=grey]"
+		}
+		fmt.Fprintf(w, "\t\"%s\" -> \"%s\"%s;\n", edge.From, edge.To, style)
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// Mermaid renders the graph as a Mermaid flowchart
+func (e Export) Mermaid(w io.Writer) {
+	ids := make(map[string]string, len(e.Nodes))
+	fmt.Fprintln(w, "flowchart TD")
+	for i, node := range e.Nodes {
+		ids[node.Name] = fmt.Sprintf("n%d", i)
+		label := strings.ReplaceAll(strings.Join(node.label(), "<br/>"), `"`, "#quot;")
+		fmt.Fprintf(w, "    %s[\"%s\"]\n", ids[node.Name], label)
+		switch {
+		case node.Missing:
+			fmt.Fprintf(w, "    style %s stroke:red,stroke-dasharray: 5 5\n", ids[node.Name])
+		case node.Outside:
+			fmt.Fprintf(w, "    style %s stroke:grey,color:grey,stroke-dasharray: 2 2\n", ids[node.Name])
+		}
+	}
+	for _, edge := range e.Edges {
+		arrow := "-->"
+		if edge.Kind != After {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(w, "    %s %s %s\n", ids[edge.From], arrow, ids[edge.To])
+	}
+}
+
+// JSON renders the graph as JSON
+func (e Export) JSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(e)
+}
+
+// Tree renders the graph as an indented tree, with each trigger followed by the
+// triggers it runs after
+func (e Export) Tree(w io.Writer) {
+	after := make(map[string][]Edge)
+	needed := make(map[string]bool)
+	for _, edge := range e.Edges {
+		after[edge.From] = append(after[edge.From], edge)
+		needed[edge.To] = true
+	}
+	var walk func(edge Edge, prefix string, last bool)
+	walk = func(edge Edge, prefix string, last bool) {
+		branch, next := "├── ", "│   "
+		if last {
+			branch, next = "└── ", "    "
+		}
+		suffix := ""
+		switch edge.Kind {
+		case Missing:
+			suffix = " (missing)"
+		case Outside:
+			suffix = " (left out)"
+		}
+		fmt.Fprintf(w, "%s%s%s%s\n", prefix, branch, edge.To, suffix)
+		for i, child := range after[edge.To] {
+			walk(child, prefix+next, i == len(after[edge.To])-1)
+		}
+	}
+	for _, node := range e.Nodes {
+		if needed[node.Name] {
+			continue
+		}
+		fmt.Fprintln(w, node.Name)
+		for i, edge := range after[node.Name] {
+			walk(edge, "", i == len(after[node.Name])-1)
+		}
+	}
+}
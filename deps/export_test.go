@@ -0,0 +1,78 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deps
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	g := Graph{
+		"a": {"b", "skipped", "gone"},
+		"c": {"a", "d"},
+	}
+	nodes := map[string]Node{
+		"a": {Name: "a"},
+		"b": {Name: "b"},
+		"c": {Name: "c"},
+		"d": {Name: "d"},
+	}
+	e := g.Export(nodes, []string{"a", "b", "c", "d", "skipped"})
+	wantNodes := []Node{
+		{Name: "a"},
+		{Name: "b"},
+		{Name: "c"},
+		{Name: "d"},
+		{Name: "gone", Missing: true},
+		{Name: "skipped", Outside: true},
+	}
+	if !reflect.DeepEqual(e.Nodes, wantNodes) {
+		t.Errorf("Export() nodes = %v, want %v", e.Nodes, wantNodes)
+	}
+	wantEdges := []Edge{
+		{From: "a", To: "b", Kind: After},
+		{From: "a", To: "gone", Kind: Missing},
+		{From: "a", To: "skipped", Kind: Outside},
+		{From: "c", To: "a", Kind: After},
+		{From: "c", To: "d", Kind: After},
+	}
+	if !reflect.DeepEqual(e.Edges, wantEdges) {
+		t.Errorf("Export() edges = %v, want %v", e.Edges, wantEdges)
+	}
+
+	f := e.Filter([]string{"c", "a"})
+	wantNodes = []Node{
+		{Name: "a"},
+		{Name: "b", Outside: true},
+		{Name: "c"},
+		{Name: "d", Outside: true},
+		{Name: "gone", Missing: true},
+		{Name: "skipped", Outside: true},
+	}
+	if !reflect.DeepEqual(f.Nodes, wantNodes) {
+		t.Errorf("Filter() nodes = %v, want %v", f.Nodes, wantNodes)
+	}
+	wantEdges = []Edge{
+		{From: "a", To: "b", Kind: Outside},
+		{From: "a", To: "gone", Kind: Missing},
+		{From: "a", To: "skipped", Kind: Outside},
+		{From: "c", To: "a", Kind: After},
+		{From: "c", To: "d", Kind: Outside},
+	}
+	if !reflect.DeepEqual(f.Edges, wantEdges) {
+		t.Errorf("Filter() edges = %v, want %v", f.Edges, wantEdges)
+	}
+}
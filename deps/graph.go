@@ -15,7 +15,6 @@
 package deps
 
 import (
-	"fmt"
 	"log/slog"
 	"sort"
 	"strings"
@@ -153,31 +152,67 @@ func (g Graph) traverse(todo []string) (order, remaining []string) {
 	return
 }
 
-// Resolve finds the ideal ordering for a list of triggers
-func (g Graph) Resolve(todo []string) (order []string) {
+// Levels groups a list of triggers by the order in which they can run, where every
+// trigger only runs after those in earlier levels
+func (g Graph) Levels(todo []string) (levels [][]string) {
 	g.prune(todo)
 	var partial []string
 	for len(todo) > 0 {
 		partial, todo = g.traverse(todo)
-		order = append(order, partial...)
+		levels = append(levels, partial)
 	}
 	return
 }
 
-// Print renders this graph to a "dot" format
-func (g Graph) Print() {
-	var names []string
-	for name := range g {
-		names = append(names, name)
+// Resolve finds the ideal ordering for a list of triggers
+func (g Graph) Resolve(todo []string) (order []string) {
+	for _, level := range g.Levels(todo) {
+		order = append(order, level...)
 	}
-	sort.Strings(names)
-	fmt.Println("digraph {")
-	for _, name := range names {
-		deps := g[name]
-		sort.Strings(deps)
-		for _, dep := range deps {
-			fmt.Printf("\t\"%s\" -> \"%s\";\n", name, dep)
+	return
+}
+
+// Dependencies finds every trigger which must run before the named one, directly or not
+func (g Graph) Dependencies(name string) (found []string) {
+	seen := map[string]bool{name: true}
+	todo := []string{name}
+	for len(todo) > 0 {
+		next := todo[0]
+		todo = todo[1:]
+		for _, dep := range g[next] {
+			if seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			found = append(found, dep)
+			todo = append(todo, dep)
 		}
 	}
-	fmt.Println("}")
+	sort.Strings(found)
+	return
+}
+
+// Dependents finds every trigger which must run after the named one, directly or not
+func (g Graph) Dependents(name string) (found []string) {
+	seen := map[string]bool{name: true}
+	todo := []string{name}
+	for len(todo) > 0 {
+		next := todo[0]
+		todo = todo[1:]
+		for parent, deps := range g {
+			if seen[parent] {
+				continue
+			}
+			for _, dep := range deps {
+				if dep == next {
+					seen[parent] = true
+					found = append(found, parent)
+					todo = append(todo, parent)
+					break
+				}
+			}
+		}
+	}
+	sort.Strings(found)
+	return
 }
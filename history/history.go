@@ -0,0 +1,205 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package history
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+
+	"github.com/getsolus/usysconf/state"
+)
+
+// Entry is the outcome of a single trigger within a run
+type Entry struct {
+	Name     string        `cbor:"name" json:"name"`
+	Status   string        `cbor:"status" json:"status"`
+	Duration time.Duration `cbor:"duration" json:"duration"`
+	Changed  int           `cbor:"changed" json:"changed"`
+	Message  string        `cbor:"message,omitempty" json:"message,omitempty"`
+}
+
+// Record describes a single invocation of "usysconf run"
+type Record struct {
+	ID       string        `cbor:"id" json:"id"`
+	Command  []string      `cbor:"command" json:"command"`
+	Start    time.Time     `cbor:"start" json:"start"`
+	Duration time.Duration `cbor:"duration" json:"duration"`
+	Changed  int           `cbor:"changed" json:"changed"`
+	Triggers []Entry       `cbor:"triggers" json:"triggers"`
+}
+
+// MaxRecords is the number of runs kept in the history store. Older runs are removed
+// as new ones are appended.
+const MaxRecords = 1000
+
+// NewID generates an identifier for a run that started at the given time
+func NewID(start time.Time) string {
+	return start.UTC().Format("20060102T150405.000Z")
+}
+
+// Path gets the location of the history store, which lives next to the state
+func Path() string {
+	return filepath.Join(filepath.Dir(state.Path), "history")
+}
+
+// Append adds a record to the end of the history store, first cutting off any
+// incomplete record left behind by an interrupted append. The store is rewritten
+// instead when it holds corrupt records, or when the oldest runs must be removed to
+// keep it within MaxRecords.
+func Append(r Record) error {
+	if err := os.MkdirAll(filepath.Dir(Path()), 0750); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Clean(Path()), os.O_RDWR|os.O_CREATE, 0640)
+	if err != nil {
+		return err
+	}
+	records, valid, skipped, err := decode(f)
+	if err == nil && (skipped > 0 || len(records) >= MaxRecords) {
+		_ = f.Close()
+		if len(records) >= MaxRecords {
+			records = records[len(records)-MaxRecords+1:]
+		}
+		return Save(append(records, r))
+	}
+	if err == nil {
+		if err = f.Truncate(valid); err == nil {
+			_, err = f.Seek(valid, io.SeekStart)
+		}
+	}
+	if err == nil {
+		err = cbor.NewEncoder(f).Encode(r)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Load reads every record in the history store, oldest first
+func Load() ([]Record, error) {
+	f, err := os.Open(filepath.Clean(Path()))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	records, _, _, err := decode(f)
+	return records, err
+}
+
+// decode reads records until the end of the store, along with the length of the
+// readable part and the number of records skipped. Records which aren't a Record are
+// skipped, and an incomplete or malformed end of the store is left out, each with a
+// warning.
+func decode(r io.Reader) (records []Record, valid int64, skipped int, err error) {
+	dec := cbor.NewDecoder(r)
+	for {
+		var raw cbor.RawMessage
+		if err = dec.Decode(&raw); err != nil {
+			var syntax *cbor.SyntaxError
+			var semantic *cbor.SemanticError
+			switch {
+			case errors.Is(err, io.EOF):
+				err = nil
+			case errors.Is(err, io.ErrUnexpectedEOF):
+				slog.Warn("Ignoring incomplete record at the end of the history", "path", Path())
+				err = nil
+			case errors.As(err, &syntax), errors.As(err, &semantic):
+				slog.Warn("Ignoring unreadable data at the end of the history", "path", Path(), "reason", err)
+				skipped++
+				err = nil
+			}
+			return
+		}
+		valid = int64(dec.NumBytesRead())
+		var rec Record
+		if uerr := cbor.Unmarshal(raw, &rec); uerr != nil {
+			slog.Warn("Skipping corrupt record in the history", "path", Path(), "reason", uerr)
+			skipped++
+			continue
+		}
+		records = append(records, rec)
+	}
+}
+
+// Save replaces the history store with the provided records
+func Save(records []Record) error {
+	var buff bytes.Buffer
+	enc := cbor.NewEncoder(&buff)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	tmp := Path() + ".tmp"
+	if err := os.WriteFile(tmp, buff.Bytes(), 0640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, Path())
+}
+
+// Prune removes records older than maxAge, followed by the oldest records until
+// the store fits within maxSize bytes. A zero value disables either limit.
+func Prune(maxAge time.Duration, maxSize int64) (removed int, err error) {
+	records, err := Load()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read history: %w", err)
+	}
+	total := len(records)
+	if maxAge > 0 {
+		cutoff := time.Now().Add(-maxAge)
+		var kept []Record
+		for _, r := range records {
+			if r.Start.After(cutoff) {
+				kept = append(kept, r)
+			}
+		}
+		records = kept
+	}
+	if maxSize > 0 {
+		var sizes []int64
+		var size int64
+		for _, r := range records {
+			raw, err := cbor.Marshal(r)
+			if err != nil {
+				return 0, err
+			}
+			sizes = append(sizes, int64(len(raw)))
+			size += int64(len(raw))
+		}
+		for len(records) > 0 && size > maxSize {
+			size -= sizes[0]
+			sizes = sizes[1:]
+			records = records[1:]
+		}
+	}
+	removed = total - len(records)
+	if removed == 0 {
+		return
+	}
+	err = Save(records)
+	return
+}
@@ -0,0 +1,96 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+
+	"github.com/getsolus/usysconf/state"
+)
+
+// useStore points the history store at a temporary directory
+func useStore(t *testing.T) {
+	old := state.Path
+	state.Path = filepath.Join(t.TempDir(), "state")
+	t.Cleanup(func() { state.Path = old })
+}
+
+// ids lists the IDs of the records in the store
+func ids(t *testing.T) []string {
+	records, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	var found []string
+	for _, r := range records {
+		found = append(found, r.ID)
+	}
+	return found
+}
+
+func TestCorruptRecords(t *testing.T) {
+	useStore(t)
+	var raw []byte
+	for _, item := range []any{Record{ID: "a"}, "not a record", Record{ID: "b"}} {
+		b, err := cbor.Marshal(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw = append(raw, b...)
+	}
+	// Truncated record at the end, as left by an interrupted append
+	raw = append(raw, 0xa1)
+	if err := os.WriteFile(Path(), raw, 0640); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(ids(t)); got != "[a b]" {
+		t.Errorf("Load() = %s, want [a b]", got)
+	}
+	if err := Append(Record{ID: "c"}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	f, err := os.Open(Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, _, skipped, err := decode(f)
+	if err != nil || skipped != 0 || len(records) != 3 {
+		t.Errorf("decode() = %d records, %d skipped, %v, want 3 records, none skipped", len(records), skipped, err)
+	}
+}
+
+func TestAppendPrunes(t *testing.T) {
+	useStore(t)
+	records := make([]Record, MaxRecords)
+	for i := range records {
+		records[i].ID = fmt.Sprint(i)
+	}
+	if err := Save(records); err != nil {
+		t.Fatal(err)
+	}
+	if err := Append(Record{ID: "new"}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	found := ids(t)
+	if len(found) != MaxRecords || found[0] != "1" || found[len(found)-1] != "new" {
+		t.Errorf("Append() kept %d records from %s to %s, want %d from 1 to new", len(found), found[0], found[len(found)-1], MaxRecords)
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// JournalSocket is the location of the journald native protocol socket
+const JournalSocket = "/run/systemd/journal/socket"
+
+// fieldPrefix is added to every attribute to namespace it in the journal
+const fieldPrefix = "USYSCONF_"
+
+// truncatedSize is the length that values are cut down to when a record is too
+// large to be sent in a single datagram, such as one with a lot of captured output
+const truncatedSize = 16 * 1024
+
+// JournalHandler writes records to journald using its native protocol
+type JournalHandler struct {
+	conn   *net.UnixConn
+	lock   *sync.Mutex
+	level  slog.Leveler
+	prefix string
+	attrs  []slog.Attr
+}
+
+// NewJournalHandler connects to the journald socket
+func NewJournalHandler(level slog.Leveler) (*JournalHandler, error) {
+	addr := &net.UnixAddr{Name: JournalSocket, Net: "unixgram"}
+	conn, err := net.DialUnix("unixgram", nil, addr)
+	if err != nil {
+		return nil, err
+	}
+	return &JournalHandler{
+		conn:   conn,
+		lock:   &sync.Mutex{},
+		level:  level,
+		prefix: fieldPrefix,
+	}, nil
+}
+
+// Enabled reports whether the level is high enough to be logged
+func (j *JournalHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= j.level.Level()
+}
+
+// Handle serializes a record and sends it to the journal
+func (j *JournalHandler) Handle(_ context.Context, r slog.Record) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	_, err := j.conn.Write(j.encode(r, 0))
+	if errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS) {
+		// Too large for one datagram, so cut down the long values and try again
+		_, err = j.conn.Write(j.encode(r, truncatedSize))
+	}
+	return err
+}
+
+// encode serializes a record, cutting down values longer than limit, if set
+func (j *JournalHandler) encode(r slog.Record, limit int) []byte {
+	var buff bytes.Buffer
+	writeField(&buff, "MESSAGE", r.Message, limit)
+	writeField(&buff, "PRIORITY", strconv.Itoa(severity(r.Level)), limit)
+	writeField(&buff, "SYSLOG_IDENTIFIER", "usysconf", limit)
+	for _, attr := range j.attrs {
+		writeAttr(&buff, "", attr, limit)
+	}
+	r.Attrs(func(attr slog.Attr) bool {
+		writeAttr(&buff, j.prefix, attr, limit)
+		return true
+	})
+	return buff.Bytes()
+}
+
+// Close disconnects from the journal
+func (j *JournalHandler) Close() error {
+	return j.conn.Close()
+}
+
+// WithAttrs returns a handler that includes the attributes in every record
+func (j *JournalHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *j
+	next.attrs = append([]slog.Attr{}, j.attrs...)
+	for _, attr := range attrs {
+		attr.Key = j.prefix + attr.Key
+		next.attrs = append(next.attrs, attr)
+	}
+	return &next
+}
+
+// WithGroup returns a handler that nests future attributes under a group
+func (j *JournalHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return j
+	}
+	next := *j
+	next.prefix = j.prefix + name + "_"
+	return &next
+}
+
+// writeAttr adds an attribute, flattening groups into prefixed field names
+func writeAttr(buff *bytes.Buffer, prefix string, attr slog.Attr, limit int) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Value.Kind() == slog.KindGroup {
+		for _, sub := range attr.Value.Group() {
+			writeAttr(buff, prefix+attr.Key+"_", sub, limit)
+		}
+		return
+	}
+	if attr.Key == "" {
+		return
+	}
+	writeField(buff, prefix+attr.Key, attr.Value.String(), limit)
+}
+
+// writeField encodes a single field, using the binary form for multi-line values, and
+// keeping only the start of values longer than limit, if set
+func writeField(buff *bytes.Buffer, key, value string, limit int) {
+	key = fieldName(key)
+	if limit > 0 && len(value) > limit {
+		value = fmt.Sprintf("%s\n[truncated %d bytes]", strings.ToValidUTF8(value[:limit], ""), len(value)-limit)
+	}
+	if !strings.Contains(value, "\n") {
+		fmt.Fprintf(buff, "%s=%s\n", key, value)
+		return
+	}
+	buff.WriteString(key)
+	buff.WriteByte('\n')
+	_ = binary.Write(buff, binary.LittleEndian, uint64(len(value)))
+	buff.WriteString(value)
+	buff.WriteByte('\n')
+}
+
+// fieldName converts a key into the upper-case form required by journald
+func fieldName(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+}
+
+// severity maps a log level to a syslog severity
+func severity(level slog.Level) int {
+	switch {
+	case level >= slog.LevelError:
+		return 3
+	case level >= slog.LevelWarn:
+		return 4
+	case level >= slog.LevelInfo:
+		return 6
+	default:
+		return 7
+	}
+}
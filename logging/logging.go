@@ -0,0 +1,144 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Dir is the path defined during build (Makefile) i.e. /var/log/usysconf
+var Dir string
+
+// Options configures where and how log records are written.
+type Options struct {
+	Debug   bool
+	Format  string
+	File    string
+	Journal bool
+	Syslog  bool
+}
+
+var (
+	current Options
+	files   []*os.File
+	journal *JournalHandler
+	sysLog  *SyslogHandler
+	console io.Writer = os.Stderr
+	quiet   bool
+)
+
+// Setup replaces the default logger with one built from the provided options,
+// closing the files and connections of the previous one.
+func Setup(o Options) error {
+	closeAll()
+	current = o
+	if o.File != "" {
+		f, err := os.OpenFile(o.File, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0640)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		files = append(files, f)
+	}
+	return rebuild()
+}
+
+// AttachRunLog rotates the persistent logs in Dir and adds a fresh log for this run.
+func AttachRunLog() error {
+	if Dir == "" {
+		return nil
+	}
+	f, err := rotate(Dir)
+	if err != nil {
+		return err
+	}
+	files = append(files, f)
+	return rebuild()
+}
+
+// closeAll closes the log files and disconnects from the journal and syslog
+func closeAll() {
+	for _, f := range files {
+		_ = f.Close()
+	}
+	files = nil
+	if journal != nil {
+		_ = journal.Close()
+		journal = nil
+	}
+	if sysLog != nil {
+		_ = sysLog.Close()
+		sysLog = nil
+	}
+}
+
+// SetConsole redirects the console output, optionally hiding anything below a warning.
+func SetConsole(w io.Writer, quietConsole bool) error {
+	console = w
+	quiet = quietConsole
+	return rebuild()
+}
+
+// rebuild assembles the handlers for the current options and installs them, reusing
+// the connections to the journal and syslog
+func rebuild() error {
+	level := slog.LevelInfo
+	if current.Debug {
+		level = slog.LevelDebug
+	}
+	consoleLevel := level
+	if quiet && !current.Debug {
+		consoleLevel = slog.LevelWarn
+	}
+	handlers := []slog.Handler{
+		newHandler(current.Format, console, consoleLevel),
+	}
+	for _, f := range files {
+		handlers = append(handlers, newHandler(current.Format, f, slog.LevelDebug))
+	}
+	if current.Journal {
+		if journal == nil {
+			j, err := NewJournalHandler(level)
+			if err != nil {
+				return fmt.Errorf("failed to connect to journald: %w", err)
+			}
+			journal = j
+		}
+		handlers = append(handlers, journal)
+	}
+	if current.Syslog {
+		if sysLog == nil {
+			h, err := NewSyslogHandler(level)
+			if err != nil {
+				return fmt.Errorf("failed to connect to syslog: %w", err)
+			}
+			sysLog = h
+		}
+		handlers = append(handlers, sysLog)
+	}
+	slog.SetDefault(slog.New(Multi(handlers)))
+	return nil
+}
+
+// newHandler creates a text or JSON handler for a writer
+func newHandler(format string, w io.Writer, level slog.Leveler) slog.Handler {
+	opts := &slog.HandlerOptions{Level: level}
+	if format == "json" {
+		return slog.NewJSONHandler(w, opts)
+	}
+	return slog.NewTextHandler(w, opts)
+}
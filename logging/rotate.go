@@ -0,0 +1,50 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Keep is the number of previous run logs to retain
+const Keep = 10
+
+// runLog is the name of the log for the most recent run
+const runLog = "usysconf.log"
+
+// rotate shifts the existing run logs down by one and opens a new one
+func rotate(dir string) (*os.File, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	base := filepath.Join(dir, runLog)
+	_ = os.Remove(fmt.Sprintf("%s.%d", base, Keep))
+	for i := Keep - 1; i > 0; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", base, i), fmt.Sprintf("%s.%d", base, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to rotate logs: %w", err)
+		}
+	}
+	if err := os.Rename(base, base+".1"); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to rotate logs: %w", err)
+	}
+	f, err := os.OpenFile(base, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open run log: %w", err)
+	}
+	return f, nil
+}
@@ -0,0 +1,114 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// SyslogSocket is the location of the local syslog socket
+const SyslogSocket = "/dev/log"
+
+// facilityDaemon is the syslog facility used for every record
+const facilityDaemon = 3
+
+// SyslogHandler writes records to the local syslog daemon, in the BSD format of RFC 3164,
+// with the attributes formatted as text after the message
+type SyslogHandler struct {
+	conn  *net.UnixConn
+	lock  *sync.Mutex
+	level slog.Leveler
+	buff  *bytes.Buffer
+	text  slog.Handler
+}
+
+// NewSyslogHandler connects to the syslog socket
+func NewSyslogHandler(level slog.Leveler) (*SyslogHandler, error) {
+	addr := &net.UnixAddr{Name: SyslogSocket, Net: "unixgram"}
+	conn, err := net.DialUnix("unixgram", nil, addr)
+	if err != nil {
+		return nil, err
+	}
+	buff := &bytes.Buffer{}
+	text := slog.NewTextHandler(buff, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		// The header already has the time and level
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if len(groups) == 0 && (attr.Key == slog.TimeKey || attr.Key == slog.LevelKey) {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	})
+	return &SyslogHandler{
+		conn:  conn,
+		lock:  &sync.Mutex{},
+		level: level,
+		buff:  buff,
+		text:  text,
+	}, nil
+}
+
+// Enabled reports whether the level is high enough to be logged
+func (h *SyslogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle formats a record and sends it to syslog
+func (h *SyslogHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.buff.Reset()
+	if err := h.text.Handle(ctx, r); err != nil {
+		return err
+	}
+	body := strings.TrimSuffix(h.buff.String(), "\n")
+	header := fmt.Sprintf("<%d>%s usysconf[%d]: ", facilityDaemon*8+severity(r.Level), r.Time.Format("Jan _2 15:04:05"), os.Getpid())
+	_, err := h.conn.Write([]byte(header + body))
+	if (errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS)) && len(body) > truncatedSize {
+		// Too large for one datagram, so keep only the start of the record
+		body = fmt.Sprintf("%s [truncated %d bytes]", strings.ToValidUTF8(body[:truncatedSize], ""), len(body)-truncatedSize)
+		_, err = h.conn.Write([]byte(header + body))
+	}
+	return err
+}
+
+// WithAttrs returns a handler that includes the attributes in every record
+func (h *SyslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.text = h.text.WithAttrs(attrs)
+	return &next
+}
+
+// WithGroup returns a handler that nests future attributes under a group
+func (h *SyslogHandler) WithGroup(name string) slog.Handler {
+	next := *h
+	next.text = h.text.WithGroup(name)
+	return &next
+}
+
+// Close disconnects from syslog
+func (h *SyslogHandler) Close() error {
+	return h.conn.Close()
+}
@@ -19,18 +19,19 @@ import (
 	"os"
 
 	"github.com/getsolus/usysconf/cli"
+	"github.com/getsolus/usysconf/logging"
 )
 
 func main() {
 	ctx, flags := cli.Parse()
 
-	logOpt := &slog.HandlerOptions{}
-	if flags.Debug {
-		logOpt.Level = slog.LevelDebug
+	err := logging.Setup(flags.Logging())
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
 	}
-	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, logOpt)))
 
-	err := ctx.Run(flags)
+	err = ctx.Run(flags)
 	if err != nil {
 		slog.Error(err.Error())
 		os.Exit(1)
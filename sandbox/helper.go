@@ -0,0 +1,333 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sandbox
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// prSetNoNewPrivs is missing from the syscall package
+const prSetNoNewPrivs = 38
+
+// linuxCapabilityVersion3 is the capset ABI that supports 64 capabilities
+const linuxCapabilityVersion3 = 0x20080522
+
+// ioprioWhoProcess is missing from the syscall package
+const ioprioWhoProcess = 1
+
+// Exec sets up the sandbox and limits described in the environment and replaces the current
+// process with the binary. It only returns on failure, which is also reported to the parent.
+func Exec(argv []string) error {
+	if len(argv) == 0 {
+		return errors.New("no binary to execute")
+	}
+	var req request
+	if err := json.Unmarshal([]byte(os.Getenv(EnvVar)), &req); err != nil {
+		return fmt.Errorf("invalid sandbox request: %w", err)
+	}
+	err := req.exec(argv)
+	if req.Report != 0 {
+		report := os.NewFile(uintptr(req.Report), "report")
+		fmt.Fprint(report, err)
+		_ = report.Close()
+	}
+	return err
+}
+
+// exec carries out a request, replacing the current process with the binary
+func (req request) exec(argv []string) error {
+	// Thread-specific attributes must be set on the thread which calls exec
+	runtime.LockOSThread()
+	c := req.Sandbox
+	if c != nil {
+		if err := c.mount(); err != nil {
+			return err
+		}
+		if err := c.dropBounding(); err != nil {
+			return err
+		}
+	}
+	if cred := req.Credential; cred != nil {
+		if !cred.NoSetGroups {
+			if err := setgroups(cred.Groups); err != nil {
+				return fmt.Errorf("failed to set groups: %w", err)
+			}
+		}
+		if err := syscall.Setgid(int(cred.Gid)); err != nil {
+			return fmt.Errorf("failed to set gid: %w", err)
+		}
+		if err := syscall.Setuid(int(cred.Uid)); err != nil {
+			return fmt.Errorf("failed to set uid: %w", err)
+		}
+	}
+	// Limits come after switching user, so that only root can raise them
+	if l := req.Limits; l != nil {
+		if err := l.apply(); err != nil {
+			return err
+		}
+	}
+	if c != nil {
+		// Switching to another user already clears the capabilities
+		if syscall.Getuid() == 0 {
+			if err := c.setCapabilities(); err != nil {
+				return err
+			}
+		}
+		if c.NoNewPrivs {
+			if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0); errno != 0 {
+				return fmt.Errorf("failed to set no_new_privs: %w", errno)
+			}
+		}
+	}
+	path, err := exec.LookPath(argv[0])
+	if err != nil {
+		return err
+	}
+	var env []string
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, EnvVar+"=") {
+			env = append(env, kv)
+		}
+	}
+	// The binary must not hold the report open, or the parent would wait for it
+	if req.Report != 0 {
+		syscall.CloseOnExec(req.Report)
+	}
+	return syscall.Exec(path, argv, env)
+}
+
+// apply sets the priorities of the current thread, which are inherited by the binary
+// it executes, and the resource limits of the process
+func (l Limits) apply() error {
+	if l.Nice != nil {
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, 0, *l.Nice); err != nil {
+			return fmt.Errorf("failed to set nice: %w", err)
+		}
+	}
+	if l.IOPrio != nil {
+		_, _, errno := syscall.RawSyscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, 0, uintptr(*l.IOPrio))
+		if errno != 0 {
+			return fmt.Errorf("failed to set ionice: %w", errno)
+		}
+	}
+	for resource, value := range l.Rlimits {
+		limit := syscall.Rlimit{Cur: value, Max: value}
+		if err := syscall.Setrlimit(resource, &limit); err != nil {
+			return fmt.Errorf("failed to set rlimit %d: %w", resource, err)
+		}
+	}
+	return nil
+}
+
+// mount makes the root read-only except for the writable paths, and gives a private /tmp
+func (c Config) mount() error {
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("failed to make mounts private: %w", err)
+	}
+	if c.ReadOnly {
+		// Turn each writable path into its own mount so it can stay writable
+		for _, path := range c.Writable {
+			if err := syscall.Mount(path, path, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+				return fmt.Errorf("failed to bind '%s': %w", path, err)
+			}
+		}
+		mounts, err := mountPoints()
+		if err != nil {
+			return err
+		}
+		for _, mp := range mounts {
+			if c.keepWritable(mp) {
+				continue
+			}
+			if err = readOnly(mp); err != nil {
+				return err
+			}
+		}
+	}
+	if c.PrivateTmp {
+		if err := syscall.Mount("tmpfs", "/tmp", "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=1777"); err != nil {
+			return fmt.Errorf("failed to mount private /tmp: %w", err)
+		}
+	}
+	return nil
+}
+
+// readOnly remounts a mount point read-only, keeping its other flags. Leaving out flags
+// such as nosuid would clear them, and the kernel refuses to clear locked ones.
+func readOnly(mp string) error {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(mp, &st); err != nil {
+		return fmt.Errorf("failed to read the flags of '%s': %w", mp, err)
+	}
+	flags := syscall.MS_BIND | syscall.MS_REMOUNT | syscall.MS_RDONLY | remountFlags(int64(st.Flags))
+	if err := syscall.Mount("", mp, "", flags, ""); err != nil {
+		return fmt.Errorf("failed to make '%s' read-only: %w", mp, err)
+	}
+	return nil
+}
+
+// Mount flags reported by statfs, which are missing from the syscall package
+const (
+	stNoSuid      = 0x2
+	stNoDev       = 0x4
+	stNoExec      = 0x8
+	stSynchronous = 0x10
+	stMandLock    = 0x40
+	stNoAtime     = 0x400
+	stNoDirAtime  = 0x800
+	stRelAtime    = 0x1000
+)
+
+// remountFlags converts the flags reported by statfs to the matching mount flags
+func remountFlags(statfs int64) (flags uintptr) {
+	for _, flag := range []struct {
+		st    int64
+		mount uintptr
+	}{
+		{stNoSuid, syscall.MS_NOSUID},
+		{stNoDev, syscall.MS_NODEV},
+		{stNoExec, syscall.MS_NOEXEC},
+		{stSynchronous, syscall.MS_SYNCHRONOUS},
+		{stMandLock, syscall.MS_MANDLOCK},
+		{stNoAtime, syscall.MS_NOATIME},
+		{stNoDirAtime, syscall.MS_NODIRATIME},
+		{stRelAtime, syscall.MS_RELATIME},
+	} {
+		if statfs&flag.st != 0 {
+			flags |= flag.mount
+		}
+	}
+	return
+}
+
+// keepWritable checks if a mount point is a writable path, or an API filesystem
+func (c Config) keepWritable(mp string) bool {
+	for _, prefix := range append([]string{"/proc", "/sys", "/dev", "/run"}, c.Writable...) {
+		prefix = filepath.Clean(prefix)
+		if mp == prefix || strings.HasPrefix(mp, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// mountPoints lists every mount point visible to this process, parents first
+func mountPoints() (mounts []string, err error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 4 {
+			mounts = append(mounts, unescape(fields[4]))
+		}
+	}
+	sort.Strings(mounts)
+	return mounts, scanner.Err()
+}
+
+// unescape decodes the octal escapes used for spaces and tabs in mountinfo
+func unescape(path string) string {
+	r := strings.NewReplacer(`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`)
+	return r.Replace(path)
+}
+
+// kept gets the mask of capabilities which should not be dropped
+func (c Config) kept() (keep uint64) {
+	for _, name := range c.Capabilities {
+		keep |= 1 << capabilities[name]
+	}
+	return
+}
+
+// dropBounding removes every capability not explicitly kept from the bounding set,
+// so that they cannot be regained by executing a binary
+func (c Config) dropBounding() error {
+	if !c.DropCapabilities {
+		return nil
+	}
+	keep := c.kept()
+	last := lastCapability()
+	for capability := 0; capability <= last; capability++ {
+		if keep&(1<<capability) != 0 {
+			continue
+		}
+		_, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, syscall.PR_CAPBSET_DROP, uintptr(capability), 0)
+		if errno != 0 && errno != syscall.EINVAL {
+			return fmt.Errorf("failed to drop capability %d: %w", capability, errno)
+		}
+	}
+	return nil
+}
+
+// setCapabilities limits the current capabilities to those explicitly kept
+func (c Config) setCapabilities() error {
+	if !c.DropCapabilities {
+		return nil
+	}
+	keep := c.kept()
+	header := struct {
+		version uint32
+		pid     int32
+	}{version: linuxCapabilityVersion3}
+	data := [2]struct {
+		effective   uint32
+		permitted   uint32
+		inheritable uint32
+	}{}
+	data[0].effective, data[0].permitted = uint32(keep), uint32(keep)
+	data[1].effective, data[1].permitted = uint32(keep>>32), uint32(keep>>32)
+	_, _, errno := syscall.RawSyscall(syscall.SYS_CAPSET, uintptr(unsafe.Pointer(&header)), uintptr(unsafe.Pointer(&data[0])), 0)
+	if errno != 0 {
+		return fmt.Errorf("failed to set capabilities: %w", errno)
+	}
+	return nil
+}
+
+// lastCapability gets the highest capability known to the kernel
+func lastCapability() int {
+	raw, err := os.ReadFile("/proc/sys/kernel/cap_last_cap")
+	if err != nil {
+		return 40
+	}
+	var last int
+	if _, err = fmt.Sscanf(string(raw), "%d", &last); err != nil {
+		return 40
+	}
+	return last
+}
+
+// setgroups sets the supplementary groups for every thread
+func setgroups(groups []uint32) error {
+	gids := make([]int, len(groups))
+	for i, g := range groups {
+		gids[i] = int(g)
+	}
+	return syscall.Setgroups(gids)
+}
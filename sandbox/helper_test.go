@@ -0,0 +1,73 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package sandbox
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestKeepWritable(t *testing.T) {
+	c := Config{Writable: []string{"/var/cache/fontconfig/", "/srv"}}
+	tests := []struct {
+		mp   string
+		want bool
+	}{
+		{"/", false},
+		{"/proc", true},
+		{"/proc/sys/fs/binfmt_misc", true},
+		{"/sys/fs/cgroup", true},
+		{"/dev/shm", true},
+		{"/run/user/1000", true},
+		{"/running", false},
+		{"/var/cache/fontconfig", true},
+		{"/var/cache/fontconfig/sub", true},
+		{"/var/cache", false},
+		{"/srv", true},
+		{"/srvx", false},
+		{"/usr", false},
+	}
+	for _, tt := range tests {
+		if got := c.keepWritable(tt.mp); got != tt.want {
+			t.Errorf("keepWritable(%q) = %v, want %v", tt.mp, got, tt.want)
+		}
+	}
+}
+
+func TestRemountFlags(t *testing.T) {
+	tests := []struct {
+		name   string
+		statfs int64
+		want   uintptr
+	}{
+		{"none", 0, 0},
+		{"read-only is added separately", 0x1, 0},
+		{"nosuid and nodev", stNoSuid | stNoDev, syscall.MS_NOSUID | syscall.MS_NODEV},
+		{"noexec", stNoExec, syscall.MS_NOEXEC},
+		{"atime", stNoAtime | stNoDirAtime | stRelAtime, syscall.MS_NOATIME | syscall.MS_NODIRATIME | syscall.MS_RELATIME},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := remountFlags(tt.statfs); got != tt.want {
+				t.Errorf("remountFlags(%#x) = %#x, want %#x", tt.statfs, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	if got, want := unescape(`/mnt/my\040disk\011x\134y`), "/mnt/my disk\tx\\y"; got != want {
+		t.Errorf("unescape() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sandbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+)
+
+// EnvVar carries the sandbox request from the parent to the helper process
+const EnvVar = "USYSCONF_SANDBOX"
+
+// HelperCommand is the hidden sub-command which sets up the sandbox before executing a binary
+const HelperCommand = "sandbox-exec"
+
+// Config contains the restrictions placed on a binary.
+type Config struct {
+	ReadOnly         bool     `toml:"read_only,omitempty" json:"read_only,omitempty"`
+	Writable         []string `toml:"writable,omitempty" json:"writable,omitempty"`
+	PrivateTmp       bool     `toml:"private_tmp,omitempty" json:"private_tmp,omitempty"`
+	NoNetwork        bool     `toml:"no_network,omitempty" json:"no_network,omitempty"`
+	NoNewPrivs       bool     `toml:"no_new_privs,omitempty" json:"no_new_privs,omitempty"`
+	DropCapabilities bool     `toml:"drop_capabilities,omitempty" json:"drop_capabilities,omitempty"`
+	Capabilities     []string `toml:"capabilities,omitempty" json:"capabilities,omitempty"`
+}
+
+// Limits are the priorities and resource limits applied to a binary before it is executed.
+// Resource limits are keyed by their RLIMIT_* number.
+type Limits struct {
+	Nice    *int           `json:"nice,omitempty"`
+	IOPrio  *int           `json:"ioprio,omitempty"`
+	Rlimits map[int]uint64 `json:"rlimits,omitempty"`
+}
+
+// request is passed to the helper process
+type request struct {
+	Sandbox    *Config             `json:"sandbox,omitempty"`
+	Limits     *Limits             `json:"limits,omitempty"`
+	Credential *syscall.Credential `json:"credential,omitempty"`
+	// Report is the descriptor the helper writes to when it fails to execute the binary
+	Report int `json:"report,omitempty"`
+}
+
+// reportFD is the descriptor of the report pipe in the helper, the first of cmd.ExtraFiles
+const reportFD = 3
+
+// SetupError is a failure of the helper to execute the binary
+type SetupError struct {
+	Reason string
+}
+
+func (e *SetupError) Error() string {
+	return "sandbox helper failed: " + e.Reason
+}
+
+// Report carries failures of the helper back to the parent, apart from the exit status of
+// the binary, so that one can't be mistaken for the other
+type Report struct {
+	r, w *os.File
+}
+
+// Started closes the parent's copy of the writing end, once the helper has started
+func (r *Report) Started() {
+	if r != nil {
+		_ = r.w.Close()
+	}
+}
+
+// Close discards the report of a helper which could not be started
+func (r *Report) Close() {
+	if r != nil {
+		_ = r.w.Close()
+		_ = r.r.Close()
+	}
+}
+
+// Err gets the failure of the helper, if any, once it has exited
+func (r *Report) Err() error {
+	if r == nil {
+		return nil
+	}
+	defer r.r.Close()
+	reason, _ := io.ReadAll(r.r)
+	if len(reason) == 0 {
+		return nil
+	}
+	return &SetupError{Reason: string(reason)}
+}
+
+// Validate checks for errors in a Config
+func (c Config) Validate() error {
+	for _, path := range c.Writable {
+		if !filepath.IsAbs(path) {
+			return fmt.Errorf("writable path '%s' must be absolute", path)
+		}
+	}
+	for _, name := range c.Capabilities {
+		if _, ok := capabilities[name]; !ok {
+			return fmt.Errorf("unknown capability '%s'", name)
+		}
+	}
+	return nil
+}
+
+// Command creates a command which runs the binary through the helper, inside new namespaces
+// when there is a sandbox. The credential and limits, if any, are applied by the helper right
+// before the binary is executed, and the binary receives env, or the current environment when
+// env is nil. Failures of the helper are read from the Report once the command has exited.
+func Command(c *Config, limits *Limits, cred *syscall.Credential, env []string, name string, args ...string) (*exec.Cmd, *Report, error) {
+	raw, err := json.Marshal(request{Sandbox: c, Limits: limits, Credential: cred, Report: reportFD})
+	if err != nil {
+		return nil, nil, err
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	cmd := exec.Command("/proc/self/exe", append([]string{HelperCommand, "--", name}, args...)...)
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd.Env = append(env, EnvVar+"="+string(raw))
+	cmd.ExtraFiles = []*os.File{w}
+	report := &Report{r: r, w: w}
+	if c == nil {
+		return cmd, report, nil
+	}
+	flags := uintptr(syscall.CLONE_NEWNS | syscall.CLONE_NEWIPC)
+	if c.NoNetwork {
+		flags |= syscall.CLONE_NEWNET
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Cloneflags: flags}
+	return cmd, report, nil
+}
+
+// IsUnavailable checks if a failure to start a sandboxed command was caused by
+// namespaces not being supported or permitted. Failures to set up a sandbox inside
+// the namespaces never are, so they don't cause a fallback to running unsandboxed.
+func IsUnavailable(err error) bool {
+	var setup *SetupError
+	if errors.As(err, &setup) {
+		return false
+	}
+	return errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EINVAL) ||
+		errors.Is(err, syscall.ENOSYS) || errors.Is(err, syscall.EUSERS)
+}
@@ -0,0 +1,143 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package sandbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{
+			name:   "empty",
+			config: Config{},
+		},
+		{
+			name:   "valid",
+			config: Config{ReadOnly: true, Writable: []string{"/var/cache"}, Capabilities: []string{"CAP_CHOWN"}},
+		},
+		{
+			name:    "relative writable path",
+			config:  Config{Writable: []string{"var/cache"}},
+			wantErr: "must be absolute",
+		},
+		{
+			name:    "unknown capability",
+			config:  Config{Capabilities: []string{"CAP_MAKE_COFFEE"}},
+			wantErr: "unknown capability",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRequestEncoding(t *testing.T) {
+	nice, ioprio := 10, 7
+	tests := []struct {
+		name string
+		req  request
+	}{
+		{
+			name: "limits only",
+			req:  request{Limits: &Limits{Nice: &nice, IOPrio: &ioprio, Rlimits: map[int]uint64{7: 1024}}, Report: reportFD},
+		},
+		{
+			name: "sandbox and credential",
+			req: request{
+				Sandbox:    &Config{ReadOnly: true, Writable: []string{"/var/cache"}, NoNewPrivs: true},
+				Credential: &syscall.Credential{Uid: 1000, Gid: 1000, Groups: []uint32{10, 100}},
+				Report:     reportFD,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := json.Marshal(tt.req)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			var got request
+			if err = json.Unmarshal(raw, &got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.req) {
+				t.Errorf("decoded %+v, want %+v", got, tt.req)
+			}
+		})
+	}
+}
+
+func TestCommand(t *testing.T) {
+	cmd, report, err := Command(&Config{NoNetwork: true}, nil, nil, []string{"A=b"}, "/bin/true", "-x")
+	if err != nil {
+		t.Fatalf("Command() error = %v", err)
+	}
+	defer report.Close()
+	if want := []string{"/proc/self/exe", HelperCommand, "--", "/bin/true", "-x"}; !reflect.DeepEqual(cmd.Args, want) {
+		t.Errorf("Args = %v, want %v", cmd.Args, want)
+	}
+	if cmd.SysProcAttr.Cloneflags&syscall.CLONE_NEWNET == 0 {
+		t.Errorf("Cloneflags = %#x, want a new network namespace", cmd.SysProcAttr.Cloneflags)
+	}
+	if len(cmd.Env) != 2 || cmd.Env[0] != "A=b" {
+		t.Fatalf("Env = %v, want A=b and the request", cmd.Env)
+	}
+	var req request
+	if err = json.Unmarshal([]byte(strings.TrimPrefix(cmd.Env[1], EnvVar+"=")), &req); err != nil {
+		t.Fatalf("invalid request: %v", err)
+	}
+	if req.Sandbox == nil || !req.Sandbox.NoNetwork || req.Report != reportFD {
+		t.Errorf("request = %+v, want the sandbox and report", req)
+	}
+}
+
+func TestIsUnavailable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{fmt.Errorf("fork/exec: %w", syscall.EPERM), true},
+		{fmt.Errorf("fork/exec: %w", syscall.ENOSYS), true},
+		{fmt.Errorf("fork/exec: %w", syscall.ENOENT), false},
+		{&SetupError{Reason: "failed to make '/' read-only: operation not permitted"}, false},
+		{errors.New("exit status 1"), false},
+	}
+	for _, tt := range tests {
+		if got := IsUnavailable(tt.err); got != tt.want {
+			t.Errorf("IsUnavailable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -22,55 +22,18 @@ import (
 	"regexp"
 	"strings"
 	"time"
-
-	"github.com/fxamacker/cbor/v2"
 )
 
-// Path is the location of the serialized system state directory
-var Path string
-
 // Map contains a list files and their modification times
 type Map map[string]time.Time
 
-// Load reads in the state if it exists and deserializes it
-func Load() (Map, error) {
-	m := make(Map)
-	sFile, err := os.Open(filepath.Clean(Path))
-
-	if os.IsNotExist(err) {
-		// Don't return an error here because we need to run
-		// all of the triggers the first time to generate the file
-		return m, nil
-	}
-
-	if err != nil {
-		return nil, err
-	}
-
-	defer sFile.Close()
-
-	dec := cbor.NewDecoder(sFile)
-
-	if err := dec.Decode(&m); err != nil {
-		return nil, err
-	}
-
-	return m, nil
-}
-
-// Save writes out the current state for future runs
-func (m Map) Save() error {
-	if err := os.MkdirAll(filepath.Dir(Path), 0750); err != nil {
-		return err
-	}
-	sFile, err := os.Create(filepath.Clean(Path))
-	if err != nil {
-		return err
+// Copy creates a separate copy of a Map
+func (m Map) Copy() Map {
+	c := make(Map, len(m))
+	for k, v := range m {
+		c[k] = v
 	}
-	enc := cbor.NewEncoder(sFile)
-	err = enc.Encode(m)
-	_ = sFile.Close()
-	return err
+	return c
 }
 
 // Merge combines two Maps into one
@@ -102,6 +65,46 @@ func (m Map) Diff(curr Map) Map {
 	return diff
 }
 
+// Missing finds all of the Files which are no longer in the current state
+func (m Map) Missing(curr Map) Map {
+	missing := make(Map)
+	for k, v := range m {
+		if _, ok := curr[k]; !ok {
+			missing[k] = v
+		}
+	}
+	return missing
+}
+
+// Since finds all of the Files modified after a point in time
+func (m Map) Since(t time.Time) Map {
+	since := make(Map)
+	for k, v := range m {
+		if v.After(t) {
+			since[k] = v
+		}
+	}
+	return since
+}
+
+// Delete removes all of the Files in another Map
+func (m Map) Delete(other Map) {
+	for k := range other {
+		delete(m, k)
+	}
+}
+
+// Dangling finds all of the Files which no longer exist on the system
+func (m Map) Dangling() Map {
+	dangling := make(Map)
+	for k, v := range m {
+		if _, err := os.Lstat(k); os.IsNotExist(err) {
+			dangling[k] = v
+		}
+	}
+	return dangling
+}
+
 // Search finds all of the matching files in a Map
 func (m Map) Search(paths []string) Map {
 	match := make(Map)
@@ -122,6 +125,49 @@ func (m Map) Search(paths []string) Map {
 	return match
 }
 
+// Glob finds all of the Files matching a pattern, where "*" doesn't match "/"
+func (m Map) Glob(pattern string) (Map, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	match := make(Map)
+	for k, v := range m {
+		if ok, _ := filepath.Match(pattern, k); ok {
+			match[k] = v
+		}
+	}
+	return match, nil
+}
+
+// Within finds all of the Files matching any of the filters, or inside a directory
+// matching one, which are the Files Scan finds for them
+func (m Map) Within(filters []string) Map {
+	match := make(Map)
+	for k, v := range m {
+		for _, filter := range filters {
+			if within(k, filepath.Clean(filter)) {
+				match[k] = v
+				break
+			}
+		}
+	}
+	return match
+}
+
+// within checks if a path, or any of its parents, matches a filter
+func within(path, filter string) bool {
+	for {
+		if ok, _ := filepath.Match(filter, path); ok {
+			return true
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return false
+		}
+		path = parent
+	}
+}
+
 // Exclude removes keys from the Map if they match certain patterns
 func (m Map) Exclude(patterns []string) Map {
 	match := make(Map)
@@ -180,7 +226,7 @@ func Scan(filters []string) (m Map, err error) {
 					err = fmt.Errorf("failed to check path: %s", path)
 					return err
 				}
-				m[filepath.Join(path, info.Name())] = info.ModTime()
+				m[path] = info.ModTime()
 				return nil
 			})
 			if err != nil {
@@ -0,0 +1,98 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// keys gets the sorted keys of a Map
+func keys(m Map) []string {
+	found := m.Strings()
+	sort.Strings(found)
+	return found
+}
+
+func TestMapWithin(t *testing.T) {
+	m := Map{
+		"/usr/share/fonts":            {},
+		"/usr/share/fonts/TTF":        {},
+		"/usr/share/fonts/TTF/a.ttf":  {},
+		"/usr/share/fontconfig":       {},
+		"/usr/lib/modules/6.1/kernel": {},
+	}
+	tests := []struct {
+		name    string
+		filters []string
+		want    []string
+	}{
+		{
+			name:    "directory and its contents",
+			filters: []string{"/usr/share/fonts"},
+			want:    []string{"/usr/share/fonts", "/usr/share/fonts/TTF", "/usr/share/fonts/TTF/a.ttf"},
+		},
+		{
+			name:    "glob",
+			filters: []string{"/usr/lib/modules/*"},
+			want:    []string{"/usr/lib/modules/6.1/kernel"},
+		},
+		{
+			name:    "prefix is not a parent",
+			filters: []string{"/usr/share/font"},
+		},
+		{
+			name:    "trailing separator",
+			filters: []string{"/usr/share/fontconfig/"},
+			want:    []string{"/usr/share/fontconfig"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keys(m.Within(tt.filters)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Within(%v) = %v, want %v", tt.filters, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapDiff(t *testing.T) {
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := old.Add(time.Hour)
+	prev := Map{"/same": old, "/newer": old, "/deleted": old}
+	curr := Map{"/same": old, "/newer": now, "/new": now}
+	if got, want := keys(prev.Diff(curr)), []string{"/new", "/newer"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %v, want %v", got, want)
+	}
+	if got, want := keys(prev.Missing(curr)), []string{"/deleted"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Missing() = %v, want %v", got, want)
+	}
+}
+
+func TestMapGlob(t *testing.T) {
+	m := Map{"/a/b": {}, "/a/b/c": {}, "/a/d": {}}
+	got, err := m.Glob("/a/*")
+	if err != nil {
+		t.Fatalf("Glob() error = %v", err)
+	}
+	if want := []string{"/a/b", "/a/d"}; !reflect.DeepEqual(keys(got), want) {
+		t.Errorf("Glob() = %v, want %v", keys(got), want)
+	}
+	if _, err = m.Glob("["); err == nil {
+		t.Errorf("Glob() of an invalid pattern succeeded")
+	}
+}
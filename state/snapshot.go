@@ -0,0 +1,109 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// snapshotSuffix is the extension of saved snapshots
+const snapshotSuffix = ".state"
+
+// validSnapshot restricts snapshot names to ones which are safe as file names
+var validSnapshot = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// SnapshotDir is the directory of named copies of the state, next to the state file
+func SnapshotDir() string {
+	return filepath.Join(filepath.Dir(Path), "snapshots")
+}
+
+// snapshotPath finds the file of a named snapshot
+func snapshotPath(name string) (string, error) {
+	if !validSnapshot.MatchString(name) {
+		return "", fmt.Errorf("invalid snapshot name '%s'", name)
+	}
+	return filepath.Join(SnapshotDir(), name+snapshotSuffix), nil
+}
+
+// Snapshot saves a named copy of the current state file
+func Snapshot(name string) error {
+	path, err := snapshotPath(name)
+	if err != nil {
+		return err
+	}
+	raw, err := os.ReadFile(filepath.Clean(Path))
+	if err != nil {
+		return fmt.Errorf("failed to read state: %w", err)
+	}
+	if err = os.MkdirAll(SnapshotDir(), 0750); err != nil {
+		return err
+	}
+	return os.WriteFile(path, raw, 0600)
+}
+
+// Restore replaces the current state file with a named copy
+func Restore(name string) error {
+	path, err := snapshotPath(name)
+	if err != nil {
+		return err
+	}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("snapshot '%s' does not exist", name)
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(Path), 0750); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Clean(Path), raw, 0600)
+}
+
+// LoadSnapshot reads in a named copy of the state
+func LoadSnapshot(name string) (*State, error) {
+	path, err := snapshotPath(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("snapshot '%s' does not exist", name)
+	}
+	return load(path)
+}
+
+// Snapshots lists the names of the saved snapshots
+func Snapshots() (names []string, err error) {
+	entries, err := os.ReadDir(SnapshotDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), snapshotSuffix); ok && !entry.IsDir() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return
+}
@@ -0,0 +1,221 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Path is the location of the serialized system state directory
+var Path string
+
+// ToolVersion is the version of usysconf recorded in the state files it writes
+var ToolVersion = "unknown"
+
+// Version is the version of the state file format written by this build
+const Version = 1
+
+// State is everything remembered between runs
+type State struct {
+	Paths Map `cbor:"paths" json:"paths"`
+	// Definitions holds a hash of the definition of every trigger, by name
+	Definitions map[string]string `cbor:"definitions,omitempty" json:"definitions,omitempty"`
+	// Once holds when every trigger which only runs once did so, by name
+	Once map[string]time.Time `cbor:"once,omitempty" json:"once,omitempty"`
+
+	// Version and ToolVersion describe the file the State was read from
+	Version     int    `cbor:"-" json:"-"`
+	ToolVersion string `cbor:"-" json:"-"`
+}
+
+// envelope wraps the encoded State with what is needed to read it safely
+type envelope struct {
+	Version     int    `cbor:"version"`
+	ToolVersion string `cbor:"tool_version"`
+	Checksum    []byte `cbor:"checksum"`
+	Payload     []byte `cbor:"payload"`
+}
+
+// checksum covers the header as well as the payload, so that a changed version can't
+// go unnoticed
+func (env envelope) checksum() []byte {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\x00%s\x00", env.Version, env.ToolVersion)
+	h.Write(env.Payload)
+	return h.Sum(nil)
+}
+
+// migrations upgrade the payload of each older version to the next one
+var migrations = map[int]func(payload []byte) ([]byte, error){
+	0: migrateV0,
+}
+
+// encMode keeps the full precision of modification times
+var encMode, _ = cbor.EncOptions{Time: cbor.TimeRFC3339Nano}.EncMode()
+
+// New creates an empty State
+func New() *State {
+	return &State{
+		Paths:       make(Map),
+		Definitions: make(map[string]string),
+		Once:        make(map[string]time.Time),
+		Version:     Version,
+		ToolVersion: ToolVersion,
+	}
+}
+
+// Copy creates a separate copy of a State
+func (st *State) Copy() *State {
+	c := *st
+	c.Paths = st.Paths.Copy()
+	c.Definitions = make(map[string]string, len(st.Definitions))
+	for name, hash := range st.Definitions {
+		c.Definitions[name] = hash
+	}
+	c.Once = make(map[string]time.Time, len(st.Once))
+	for name, at := range st.Once {
+		c.Once[name] = at
+	}
+	return &c
+}
+
+// Redefined checks if the definition of a trigger differs from the one recorded, if any
+func (st *State) Redefined(name, hash string) bool {
+	prev, ok := st.Definitions[name]
+	return ok && prev != hash
+}
+
+// Load reads in the state if it exists and deserializes it
+func Load() (*State, error) {
+	return load(Path)
+}
+
+// load reads in a state file if it exists and deserializes it
+func load(path string) (*State, error) {
+	raw, err := os.ReadFile(filepath.Clean(path))
+	if errors.Is(err, os.ErrNotExist) {
+		// Don't return an error here because we need to run
+		// all of the triggers the first time to generate the file
+		return New(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decode(raw)
+}
+
+// decode reads a state file of any supported version
+func decode(raw []byte) (*State, error) {
+	var fields map[string]cbor.RawMessage
+	if err := cbor.Unmarshal(raw, &fields); err != nil {
+		return nil, fmt.Errorf("state is not valid CBOR: %w", err)
+	}
+	// Version 0 was a bare Map, and paths never clash with the envelope
+	env := envelope{Payload: raw}
+	if _, ok := fields["version"]; ok {
+		if err := cbor.Unmarshal(raw, &env); err != nil {
+			return nil, fmt.Errorf("state has an invalid header: %w", err)
+		}
+		if !bytes.Equal(env.checksum(), env.Checksum) {
+			return nil, errors.New("state checksum does not match, the file is corrupt")
+		}
+	}
+	if env.Version > Version {
+		return nil, fmt.Errorf("state version %d, written by usysconf %s, is newer than the supported version %d",
+			env.Version, env.ToolVersion, Version)
+	}
+	if env.Version < 0 {
+		return nil, fmt.Errorf("state has an invalid version %d", env.Version)
+	}
+	payload := env.Payload
+	for v := env.Version; v < Version; v++ {
+		migrate, ok := migrations[v]
+		if !ok {
+			return nil, fmt.Errorf("state version %d cannot be upgraded", v)
+		}
+		var err error
+		if payload, err = migrate(payload); err != nil {
+			return nil, fmt.Errorf("failed to upgrade state from version %d: %w", v, err)
+		}
+	}
+	st := New()
+	if err := cbor.Unmarshal(payload, st); err != nil {
+		return nil, fmt.Errorf("state has an invalid payload: %w", err)
+	}
+	if st.Paths == nil {
+		st.Paths = make(Map)
+	}
+	if st.Definitions == nil {
+		st.Definitions = make(map[string]string)
+	}
+	if st.Once == nil {
+		st.Once = make(map[string]time.Time)
+	}
+	st.Version = env.Version
+	st.ToolVersion = env.ToolVersion
+	return st, nil
+}
+
+// migrateV0 wraps the bare Map of version 0, whose keys had the base name of every
+// path appended a second time, such as "/usr/share/fonts/fonts"
+func migrateV0(payload []byte) ([]byte, error) {
+	old := make(Map)
+	if err := cbor.Unmarshal(payload, &old); err != nil {
+		return nil, err
+	}
+	m := make(Map, len(old))
+	for k, v := range old {
+		if dir := filepath.Dir(k); dir != k && filepath.Base(dir) == filepath.Base(k) {
+			k = dir
+		}
+		m[k] = v
+	}
+	return encMode.Marshal(State{Paths: m})
+}
+
+// Save writes out the current state for future runs
+func (st *State) Save() error {
+	payload, err := encMode.Marshal(st)
+	if err != nil {
+		return err
+	}
+	env := envelope{
+		Version:     Version,
+		ToolVersion: ToolVersion,
+		Payload:     payload,
+	}
+	env.Checksum = env.checksum()
+	raw, err := encMode.Marshal(env)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(Path), 0750); err != nil {
+		return err
+	}
+	// Replace the file in one go, so that an interrupted save can't corrupt it
+	tmp := Path + ".tmp"
+	if err = os.WriteFile(tmp, raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Clean(Path))
+}
@@ -0,0 +1,181 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// encodeEnvelope wraps a payload the way Save does, with the given version, and lets
+// the envelope be tampered with after its checksum is computed
+func encodeEnvelope(t *testing.T, version int, payload []byte, tamper func(env *envelope)) []byte {
+	t.Helper()
+	env := envelope{Version: version, ToolVersion: "test", Payload: payload}
+	env.Checksum = env.checksum()
+	if tamper != nil {
+		tamper(&env)
+	}
+	raw, err := encMode.Marshal(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestDecode(t *testing.T) {
+	at := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	v0, err := encMode.Marshal(Map{"/usr/share/fonts": at})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1, err := encMode.Marshal(State{
+		Paths:       Map{"/usr/share/fonts": at},
+		Definitions: map[string]string{"fonts": "hash"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		raw     []byte
+		version int
+		wantErr string
+	}{
+		{
+			name:    "version 0",
+			raw:     v0,
+			version: 0,
+		},
+		{
+			name:    "current version",
+			raw:     encodeEnvelope(t, Version, v1, nil),
+			version: Version,
+		},
+		{
+			name: "bad checksum",
+			raw: encodeEnvelope(t, Version, v1, func(env *envelope) {
+				env.Checksum = make([]byte, sha256.Size)
+			}),
+			wantErr: "checksum does not match",
+		},
+		{
+			name: "changed header",
+			raw: encodeEnvelope(t, Version, v1, func(env *envelope) {
+				env.Version = 0
+			}),
+			wantErr: "checksum does not match",
+		},
+		{
+			name:    "negative version",
+			raw:     encodeEnvelope(t, -1, v1, nil),
+			wantErr: "invalid version",
+		},
+		{
+			name:    "newer version",
+			raw:     encodeEnvelope(t, Version+1, v1, nil),
+			wantErr: "is newer than the supported version",
+		},
+		{
+			name:    "not CBOR",
+			raw:     []byte("not cbor"),
+			wantErr: "not valid CBOR",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, err := decode(tt.raw)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("decode() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decode() error = %v", err)
+			}
+			if st.Version != tt.version {
+				t.Errorf("Version = %d, want %d", st.Version, tt.version)
+			}
+			if got := st.Paths["/usr/share/fonts"]; !got.Equal(at) {
+				t.Errorf("Paths = %v, want %v", st.Paths, at)
+			}
+			if st.Definitions == nil || st.Once == nil {
+				t.Errorf("Definitions and Once must not be nil")
+			}
+		})
+	}
+}
+
+func TestMigrateV0(t *testing.T) {
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	v0, err := encMode.Marshal(Map{"/usr/share/fonts/fonts": at, "/usr/share/fonts/a.ttf/a.ttf": at, "/": at})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := migrateV0(v0)
+	if err != nil {
+		t.Fatalf("migrateV0() error = %v", err)
+	}
+	st, err := decode(encodeEnvelope(t, 1, payload, nil))
+	if err != nil {
+		t.Fatalf("decode() error = %v", err)
+	}
+	want := []string{"/", "/usr/share/fonts", "/usr/share/fonts/a.ttf"}
+	if got := keys(st.Paths); !reflect.DeepEqual(got, want) || !st.Paths["/usr/share/fonts"].Equal(at) {
+		t.Errorf("Paths = %v, want %v at %v", st.Paths, want, at)
+	}
+	if _, err = migrateV0([]byte("not cbor")); err == nil {
+		t.Errorf("migrateV0() of invalid CBOR succeeded")
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	prev := Path
+	Path = filepath.Join(t.TempDir(), "state")
+	t.Cleanup(func() { Path = prev })
+	at := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	st := New()
+	st.Paths["/etc/fonts"] = at
+	st.Once["qol-assist"] = at
+	if err := st.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !loaded.Paths["/etc/fonts"].Equal(at) || !loaded.Once["qol-assist"].Equal(at) {
+		t.Errorf("Load() = %+v, want the saved state", loaded)
+	}
+	// Flip a byte inside the payload
+	raw, err := os.ReadFile(Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := strings.Index(string(raw), "/etc/fonts")
+	raw[i] = 'X'
+	if err = os.WriteFile(Path, raw, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Load(); err == nil {
+		t.Errorf("Load() of a corrupt state succeeded")
+	}
+}
@@ -15,11 +15,16 @@
 package triggers
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os/exec"
+	"sort"
+	"syscall"
+	"time"
 
+	"github.com/getsolus/usysconf/sandbox"
 	"github.com/getsolus/usysconf/util"
 )
 
@@ -28,54 +33,298 @@ type Bin struct {
 	Task    string   `toml:"task"`
 	Bin     string   `toml:"bin"`
 	Args    []string `toml:"args"`
+	User    string   `toml:"user,omitempty"`
+	Group   string   `toml:"group,omitempty"`
 	Replace *Replace `toml:"replace"`
+	Retry   *Retry   `toml:"retry,omitempty"`
+
+	OnFailure string          `toml:"on_failure,omitempty"`
+	Sandbox   *sandbox.Config `toml:"sandbox,omitempty"`
+	Nice      *int            `toml:"nice,omitempty"`
+	IONice    *IONice         `toml:"ionice,omitempty"`
+	Rlimit    *Rlimit         `toml:"rlimit,omitempty"`
+	Cgroup    *Cgroup         `toml:"cgroup,omitempty"`
+
+	SuccessCodes        []int    `toml:"success_codes,omitempty"`
+	SkipCodes           []int    `toml:"skip_codes,omitempty"`
+	FailIfOutputMatches []string `toml:"fail_if_output_matches,omitempty"`
+
+	Condition *Condition `toml:"condition,omitempty"`
 }
 
-// ExecuteBins generates and runs all of the necesarry Bin commands
-func (t *Trigger) ExecuteBins(s Scope) {
-	var bins []Bin
-	var outputs []Output
-	// Generate
+// Expand generates the Bin commands to run, with their outputs. Bins left out because
+// their conditions were not met keep their place, marked in unmet, with an output
+// explaining why.
+func (t *Trigger) Expand(s Scope) (bins []Bin, outputs []Output, unmet []bool) {
 	for _, b := range t.Bins {
+		if b.Condition != nil {
+			if v, reason := b.Condition.Evaluate(s, t.Owner); !v.Passed() {
+				bins = append(bins, b)
+				outputs = append(outputs, Output{
+					Name:    b.Task,
+					Status:  Skipped,
+					Message: fmt.Sprintf("condition not met: %s", reason),
+				})
+				unmet = append(unmet, true)
+				continue
+			}
+		}
 		bs, outs := b.FanOut()
 		bins = append(bins, bs...)
 		outputs = append(outputs, outs...)
+		unmet = append(unmet, make([]bool, len(bs))...)
 	}
+	return
+}
+
+// ExecuteBins generates and runs all of the necesarry Bin commands
+func (t *Trigger) ExecuteBins(s Scope) {
+	// Generate
+	bins, outputs, unmet := t.Expand(s)
 	// Execute
+	stopped := ""
 	for i, b := range bins {
-		out := b.Execute(s, t.Env)
+		if unmet[i] {
+			s.planSkipped(t.Name+"/"+b.Task, outputs[i].Message)
+			continue
+		}
+		if stopped != "" {
+			outputs[i].Status = Skipped
+			outputs[i].Message = fmt.Sprintf("not run because '%s' failed", stopped)
+			continue
+		}
+		label := t.Name + "/" + b.Task
+		if len(outputs[i].SubTask) > 0 {
+			label = t.Name + "/" + outputs[i].SubTask
+		}
+		out := b.Execute(s, t.Env, t.Owner, label)
 		outputs[i].Status = out.Status
 		outputs[i].Message = out.Message
+		outputs[i].Captured = out.Captured
+		outputs[i].Code = out.Code
+		outputs[i].Attempts = out.Attempts
+		s.progress(t.Name, i+1, len(bins))
+		if out.Status != Failure {
+			continue
+		}
+		switch t.policy(b) {
+		case StopRun:
+			t.abort = true
+			fallthrough
+		case StopTrigger:
+			stopped = b.Task
+		}
 	}
 	t.Output = append(t.Output, outputs...)
 }
 
-// Execute the binary from the confuration
-func (b *Bin) Execute(s Scope, env map[string]string) Output {
+// Execute the binary from the confuration as the owner of the trigger, if any,
+// labelling any streamed output
+func (b *Bin) Execute(s Scope, env map[string]string, owner, label string) Output {
 	out := Output{Status: Success}
+	// Switch user, if needed
+	cred, u, err := b.credential(owner)
+	if err != nil {
+		out.Status = Failure
+		out.Message = fmt.Sprintf("unable to find user for '%s': %s", b.Bin, err)
+		return out
+	}
+	// Setup environment
+	var environ []string
+	if cred != nil && u.Uid != "0" {
+		environ = userEnv(u)
+	}
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		environ = append(environ, fmt.Sprintf("%s=%s", k, env[k]))
+	}
 	// if the norun flag is present do not execute the configuration
 	if s.DryRun {
-		out.Status = Success
+		cmd := PlannedCommand{Label: label, Argv: append([]string{b.Bin}, b.Args...), Env: environ}
+		if cred != nil {
+			cmd.User = u.Username
+		}
+		s.planCommand(cmd)
 		return out
 	}
-	// Create command
-	cmd := exec.Command(b.Bin, b.Args...)
-	// Setup environment
-	for k, v := range env {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
-	}
-	// Add buffer for output
-	var buff bytes.Buffer
-	cmd.Stdout = &buff
-	cmd.Stderr = &buff
-	// Run the command
-	if err := cmd.Run(); err != nil {
+	// Run the command, as many times as allowed
+	delay := time.Duration(0)
+	if b.Retry != nil {
+		delay = b.Retry.Delay
+	}
+	var attempts []Attempt
+	for n := 1; ; n++ {
+		start := time.Now()
+		out = b.attempt(s, cred, environ, label)
+		attempts = append(attempts, Attempt{
+			Status:   out.Status,
+			Code:     out.Code,
+			Message:  out.Message,
+			Duration: time.Since(start),
+		})
+		out.Attempts = attempts
+		if out.Status != Failure || n >= b.Retry.attempts() || !b.Retry.allows(out.Code) {
+			break
+		}
+		slog.Debug("Retrying", "bin", b.Bin, "attempt", n+1, "delay", delay)
+		if err := s.wait(delay); err != nil {
+			break
+		}
+		delay = b.Retry.next(delay)
+	}
+	if n := len(out.Attempts); n > 1 {
+		if out.Status == Success {
+			out.Message = fmt.Sprintf("succeeded on attempt %d of %d", n, b.Retry.attempts())
+		} else {
+			out.Message = fmt.Sprintf("%s (after %d attempts)", out.Message, n)
+		}
+	}
+	return out
+}
+
+// attempt runs the binary once
+func (b *Bin) attempt(s Scope, cred *syscall.Credential, environ []string, label string) Output {
+	out := Output{Status: Success}
+	if err := s.context().Err(); err != nil {
 		out.Status = Failure
-		out.Message = fmt.Sprintf("error executing '%s %v': %s\n%s", b.Bin, b.Args, err.Error(), buff.String())
+		out.Code = -1
+		out.Message = fmt.Sprintf("not executing '%s %v': %s", b.Bin, b.Args, err)
+		return out
+	}
+	// Add buffer for output, forwarding it live and checking it if requested
+	buff := &tail{max: TailSize}
+	writers := []io.Writer{buff}
+	if s.Stream {
+		pw := newPrefixWriter(label)
+		defer pw.Flush()
+		writers = append(writers, pw)
+	}
+	patterns, _ := b.failPatterns()
+	matcher := &lineMatcher{patterns: patterns}
+	if len(patterns) > 0 {
+		writers = append(writers, matcher)
+	}
+	w := io.MultiWriter(writers...)
+	// Create command, sandboxed and inside a cgroup where possible
+	sandboxed := b.Sandbox != nil
+	if sandboxed && s.Chroot {
+		slog.Debug("Namespaces are unavailable in a chroot, running without sandbox", "bin", b.Bin)
+		sandboxed = false
+	}
+	cgroup := b.Cgroup != nil
+	var err error
+	for {
+		var cmd *exec.Cmd
+		var report *sandbox.Report
+		if cmd, report, err = b.command(cred, environ, sandboxed, b.limits(s)); err != nil {
+			break
+		}
+		err = b.start(s, cmd, report, w, label, cgroup)
+		var serr *startError
+		if !errors.As(err, &serr) || !sandbox.IsUnavailable(err) {
+			break
+		}
+		if serr.cgroup {
+			slog.Warn("Unable to start inside the cgroup, running without cgroup limits", "bin", b.Bin, "reason", serr.err)
+			cgroup = false
+			continue
+		}
+		if !sandboxed {
+			break
+		}
+		slog.Warn("Namespaces are unavailable, running without sandbox", "bin", b.Bin, "reason", serr.err)
+		sandboxed = false
+	}
+	var reason string
+	out.Status, out.Code, reason = b.status(err)
+	if out.Status == Success {
+		if line, ok := matcher.Matched(); ok {
+			out.Status = Failure
+			reason = fmt.Sprintf("output matched a failure pattern: %q", line)
+		}
+	}
+	switch out.Status {
+	case Failure:
+		out.Message = fmt.Sprintf("error executing '%s %v': %s", b.Bin, b.Args, reason)
+		out.Captured = buff.String()
+	case Skipped:
+		out.Message = fmt.Sprintf("'%s %v' %s", b.Bin, b.Args, reason)
 	}
 	return out
 }
 
+// startError is a failure to start a command, and whether it was going to start inside a cgroup
+type startError struct {
+	err    error
+	cgroup bool
+}
+
+func (e *startError) Error() string {
+	return e.err.Error()
+}
+
+func (e *startError) Unwrap() error {
+	return e.err
+}
+
+// start runs the command to completion, inside its cgroup if requested. Failures of the
+// helper, if the command runs through it, take precedence over the exit status.
+func (b *Bin) start(s Scope, cmd *exec.Cmd, report *sandbox.Report, w io.Writer, label string, cgroup bool) error {
+	cmd.Stdout = w
+	cmd.Stderr = w
+	attached := false
+	if cgroup {
+		var cleanup func()
+		cleanup, attached = b.attachCgroup(s, cmd, label)
+		defer cleanup()
+	}
+	if err := cmd.Start(); err != nil {
+		report.Close()
+		return &startError{err: err, cgroup: attached}
+	}
+	report.Started()
+	// Kill the command if the run is cancelled or times out
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-s.context().Done():
+			_ = cmd.Process.Kill()
+		case <-done:
+		}
+	}()
+	err := cmd.Wait()
+	if rerr := report.Err(); rerr != nil {
+		err = rerr
+	}
+	if ctxErr := s.context().Err(); ctxErr != nil {
+		return fmt.Errorf("%w (%s)", ctxErr, err)
+	}
+	return err
+}
+
+// command creates the command for the binary, running as the provided user. Sandboxes and
+// limits are set up by the helper right before it executes the binary, which also returns
+// a Report of its own failures.
+func (b *Bin) command(cred *syscall.Credential, env []string, sandboxed bool, limits *sandbox.Limits) (*exec.Cmd, *sandbox.Report, error) {
+	if sandboxed {
+		return sandbox.Command(b.Sandbox, limits, cred, env, b.Bin, b.Args...)
+	}
+	if limits != nil {
+		return sandbox.Command(nil, limits, cred, env, b.Bin, b.Args...)
+	}
+	cmd := exec.Command(b.Bin, b.Args...)
+	cmd.Env = env
+	if cred != nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Credential: cred}
+	}
+	return cmd, nil, nil
+}
+
 // FanOut generates one or more bin tasks from a given, as needed by replacing the "***" sequence
 // in the arguments and creating separate binaries to be executed.
 func (b Bin) FanOut() (nbins []Bin, outputs []Output) {
@@ -103,8 +352,10 @@ func (b Bin) FanOut() (nbins []Bin, outputs []Output) {
 			Name:    b.Task,
 			SubTask: path,
 		}
-		b.Args[phIndex] = path
-		nbins = append(nbins, b)
+		nb := b
+		nb.Args = append([]string(nil), b.Args...)
+		nb.Args[phIndex] = path
+		nbins = append(nbins, nb)
 		outputs = append(outputs, out)
 	}
 	return
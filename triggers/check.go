@@ -27,6 +27,14 @@ type Check struct {
 	Paths []string `toml:"paths"`
 }
 
+// Scan finds the paths matching the check, and their modification times
+func (c *Check) Scan() (state.Map, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return state.Scan(c.Paths)
+}
+
 // CheckMatch will glob the paths and if the path does not exist in the system, an error is returned
 func (t *Trigger) CheckMatch() (m state.Map, ok bool) {
 	ok = true
@@ -34,7 +42,7 @@ func (t *Trigger) CheckMatch() (m state.Map, ok bool) {
 		slog.Debug("No check paths for trigger", "name", t.Name)
 		return
 	}
-	m, err := state.Scan(t.Check.Paths)
+	m, err := t.Check.Scan()
 	if err != nil {
 		out := Output{
 			Status:  Failure,
@@ -0,0 +1,124 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+)
+
+// validateCodes checks for errors in the exit code and output rules of a Bin
+func (b *Bin) validateCodes() error {
+	for _, code := range b.SkipCodes {
+		for _, success := range b.SuccessCodes {
+			if code == success {
+				return fmt.Errorf("exit code %d cannot be both a success and a skip code", code)
+			}
+		}
+	}
+	_, err := b.failPatterns()
+	return err
+}
+
+// failPatterns compiles the regexes which mark a run as failed when the output matches
+func (b *Bin) failPatterns() (patterns []*regexp.Regexp, err error) {
+	for _, expr := range b.FailIfOutputMatches {
+		regex, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fail_if_output_matches '%s': %w", expr, err)
+		}
+		patterns = append(patterns, regex)
+	}
+	return
+}
+
+// status decides the outcome of running the bin from the error returned by the command,
+// along with its exit code, or -1 if it didn't exit normally
+func (b *Bin) status(err error) (Status, int, string) {
+	code := 0
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() < 0 {
+			return Failure, -1, err.Error()
+		}
+		code = exitErr.ExitCode()
+	}
+	for _, skip := range b.SkipCodes {
+		if code == skip {
+			return Skipped, code, fmt.Sprintf("exited with skip code %d", code)
+		}
+	}
+	if len(b.SuccessCodes) == 0 {
+		if code == 0 {
+			return Success, code, ""
+		}
+		return Failure, code, err.Error()
+	}
+	for _, success := range b.SuccessCodes {
+		if code == success {
+			return Success, code, ""
+		}
+	}
+	return Failure, code, fmt.Sprintf("exit status %d", code)
+}
+
+// lineMatcher watches output line by line and remembers the first line that matches
+type lineMatcher struct {
+	patterns []*regexp.Regexp
+	partial  []byte
+	matched  bool
+	match    string
+}
+
+// Write checks each complete line against the patterns
+func (m *lineMatcher) Write(p []byte) (int, error) {
+	m.partial = append(m.partial, p...)
+	for {
+		i := bytes.IndexByte(m.partial, '\n')
+		if i < 0 {
+			break
+		}
+		m.check(m.partial[:i])
+		m.partial = m.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// Matched checks any remaining incomplete line and gets the first match, and whether
+// there was one, since the matching line may be empty
+func (m *lineMatcher) Matched() (string, bool) {
+	if len(m.partial) > 0 {
+		m.check(m.partial)
+		m.partial = nil
+	}
+	return m.match, m.matched
+}
+
+// check compares one line to the patterns, unless a match was already found
+func (m *lineMatcher) check(line []byte) {
+	if m.matched {
+		return
+	}
+	for _, regex := range m.patterns {
+		if regex.Match(line) {
+			m.matched = true
+			m.match = string(line)
+			return
+		}
+	}
+}
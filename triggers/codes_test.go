@@ -0,0 +1,87 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package triggers
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestLineMatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		writes   []string
+		want     string
+		matched  bool
+	}{
+		{
+			name:     "no match",
+			patterns: []string{"^ERROR:"},
+			writes:   []string{"all good\n", "still good"},
+		},
+		{
+			name:     "first matching line",
+			patterns: []string{"^ERROR:"},
+			writes:   []string{"ok\nERROR: one\n", "ERROR: two\n"},
+			want:     "ERROR: one",
+			matched:  true,
+		},
+		{
+			name:     "line split across writes",
+			patterns: []string{"^ERROR: broken$"},
+			writes:   []string{"ERR", "OR: bro", "ken\nok\n"},
+			want:     "ERROR: broken",
+			matched:  true,
+		},
+		{
+			name:     "unterminated last line",
+			patterns: []string{"failed"},
+			writes:   []string{"ok\nit failed"},
+			want:     "it failed",
+			matched:  true,
+		},
+		{
+			name:     "empty line",
+			patterns: []string{"^$"},
+			writes:   []string{"ok\n\nok\n"},
+			want:     "",
+			matched:  true,
+		},
+		{
+			name:     "any of the patterns",
+			patterns: []string{"^fatal", "^panic:"},
+			writes:   []string{"panic: oops\n"},
+			want:     "panic: oops",
+			matched:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &lineMatcher{}
+			for _, expr := range tt.patterns {
+				m.patterns = append(m.patterns, regexp.MustCompile(expr))
+			}
+			for _, w := range tt.writes {
+				if n, err := m.Write([]byte(w)); n != len(w) || err != nil {
+					t.Fatalf("Write() = %d, %v", n, err)
+				}
+			}
+			got, matched := m.Matched()
+			if got != tt.want || matched != tt.matched {
+				t.Errorf("Matched() = %q, %v, want %q, %v", got, matched, tt.want, tt.matched)
+			}
+		})
+	}
+}
@@ -0,0 +1,292 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/getsolus/usysconf/util"
+)
+
+// Condition contains runtime checks which must all pass for a trigger or bin to run.
+// Unlike Skip, conditions still apply when the run is forced.
+type Condition struct {
+	Systemd   *bool       `toml:"systemd,omitempty"`
+	Container *bool       `toml:"container,omitempty"`
+	Arch      []string    `toml:"arch,omitempty"`
+	Cmdline   []string    `toml:"cmdline,omitempty"`
+	Command   []string    `toml:"command,omitempty"`
+	Env       []string    `toml:"env,omitempty"`
+	Not       *Condition  `toml:"not,omitempty"`
+	Any       []Condition `toml:"any,omitempty"`
+	All       []Condition `toml:"all,omitempty"`
+}
+
+// Verdict is whether a Condition passed
+type Verdict int
+
+const (
+	// Unmet - The condition failed.
+	Unmet Verdict = iota
+	// Met - The condition passed.
+	Met
+	// NotEvaluated - The condition needs to execute a command, which the scope doesn't allow.
+	NotEvaluated
+)
+
+// Passed checks if a Verdict lets the trigger or bin run. Conditions which could not be
+// evaluated are assumed to pass.
+func (v Verdict) Passed() bool {
+	return v != Unmet
+}
+
+// String gets a human-readable name for the Verdict
+func (v Verdict) String() string {
+	switch v {
+	case Unmet:
+		return "not met"
+	case Met:
+		return "met"
+	case NotEvaluated:
+		return "not evaluated, assumed to pass"
+	default:
+		return "unknown"
+	}
+}
+
+// Outcome is the result of evaluating a Condition, kept to decide more than once
+type Outcome struct {
+	Verdict Verdict
+	Reason  string
+}
+
+// Validate checks for errors in a Condition
+func (c *Condition) Validate() error {
+	if c.Command != nil && len(c.Command) == 0 {
+		return fmt.Errorf("condition command cannot be empty")
+	}
+	if c.Not != nil {
+		if err := c.Not.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, sub := range append(append([]Condition{}, c.Any...), c.All...) {
+		if err := sub.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Evaluate checks the condition, explaining the result either way. Commands run as the
+// owner of the trigger, if any, and are not evaluated when the scope executes nothing.
+// A check which was not evaluated is passed through Not, Any and All unchanged, unless
+// the other checks decide the result on their own.
+func (c *Condition) Evaluate(s Scope, owner string) (Verdict, string) {
+	var checks []func() (Verdict, string)
+	if c.Systemd != nil {
+		checks = append(checks, func() (Verdict, string) {
+			return expect(*c.Systemd, util.IsSystemd(), "systemd is PID1", "systemd is not PID1")
+		})
+	}
+	if c.Container != nil {
+		checks = append(checks, func() (Verdict, string) {
+			return expect(*c.Container, s.Container != "", "running in a container", "not running in a container")
+		})
+	}
+	if len(c.Arch) > 0 {
+		checks = append(checks, known(c.arch))
+	}
+	if len(c.Cmdline) > 0 {
+		checks = append(checks, known(c.cmdline))
+	}
+	if len(c.Env) > 0 {
+		checks = append(checks, known(c.env))
+	}
+	if len(c.Command) > 0 {
+		checks = append(checks, func() (Verdict, string) {
+			return c.command(s, owner)
+		})
+	}
+	if c.Not != nil {
+		checks = append(checks, func() (Verdict, string) {
+			r, reason := c.Not.Evaluate(s, owner)
+			switch r {
+			case Met:
+				r = Unmet
+			case Unmet:
+				r = Met
+			}
+			return r, fmt.Sprintf("not (%s)", reason)
+		})
+	}
+	if len(c.Any) > 0 {
+		checks = append(checks, func() (Verdict, string) {
+			return c.any(s, owner)
+		})
+	}
+	for i := range c.All {
+		sub := &c.All[i]
+		checks = append(checks, func() (Verdict, string) {
+			return sub.Evaluate(s, owner)
+		})
+	}
+	result := Met
+	var reasons []string
+	for _, check := range checks {
+		r, reason := check()
+		if r == Unmet {
+			return Unmet, reason
+		}
+		if r == NotEvaluated {
+			result = NotEvaluated
+		}
+		reasons = append(reasons, reason)
+	}
+	return result, strings.Join(reasons, ", ")
+}
+
+// Outcome evaluates the condition once, so the result can be reused
+func (c *Condition) Outcome(s Scope, owner string) *Outcome {
+	r, reason := c.Evaluate(s, owner)
+	return &Outcome{Verdict: r, Reason: reason}
+}
+
+// any passes if at least one of the sub-conditions does
+func (c *Condition) any(s Scope, owner string) (Verdict, string) {
+	result := Unmet
+	var reasons []string
+	for i := range c.Any {
+		r, reason := c.Any[i].Evaluate(s, owner)
+		if r == Met {
+			return Met, reason
+		}
+		if r == NotEvaluated {
+			result = NotEvaluated
+		}
+		reasons = append(reasons, reason)
+	}
+	if result == NotEvaluated {
+		return result, fmt.Sprintf("one of (%s)", strings.Join(reasons, "; "))
+	}
+	return result, fmt.Sprintf("none of (%s)", strings.Join(reasons, "; "))
+}
+
+// arch checks the machine architecture reported by the kernel
+func (c *Condition) arch() (bool, string) {
+	var uname syscall.Utsname
+	if err := syscall.Uname(&uname); err != nil {
+		return false, fmt.Sprintf("unable to get architecture: %s", err)
+	}
+	var b strings.Builder
+	for _, ch := range uname.Machine {
+		if ch == 0 {
+			break
+		}
+		b.WriteByte(byte(ch))
+	}
+	machine := b.String()
+	for _, arch := range c.Arch {
+		if arch == machine {
+			return true, fmt.Sprintf("architecture is %s", machine)
+		}
+	}
+	return false, fmt.Sprintf("architecture is %s, not one of %v", machine, c.Arch)
+}
+
+// cmdline checks that every option is on the kernel command line, either exactly or as
+// the name of an option with a value
+func (c *Condition) cmdline() (bool, string) {
+	raw, err := os.ReadFile("/proc/cmdline")
+	if err != nil {
+		return false, fmt.Sprintf("unable to read kernel command line: %s", err)
+	}
+	fields := strings.Fields(string(raw))
+	for _, want := range c.Cmdline {
+		found := false
+		for _, field := range fields {
+			if field == want || (!strings.Contains(want, "=") && strings.HasPrefix(field, want+"=")) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, fmt.Sprintf("kernel command line lacks '%s'", want)
+		}
+	}
+	return true, fmt.Sprintf("kernel command line has %v", c.Cmdline)
+}
+
+// env checks that every variable is set, or has the given value when written as NAME=value
+func (c *Condition) env() (bool, string) {
+	for _, want := range c.Env {
+		name, value, hasValue := strings.Cut(want, "=")
+		current, set := os.LookupEnv(name)
+		if !set {
+			return false, fmt.Sprintf("environment variable %s is not set", name)
+		}
+		if hasValue && current != value {
+			return false, fmt.Sprintf("environment variable %s is '%s', not '%s'", name, current, value)
+		}
+	}
+	return true, fmt.Sprintf("environment has %v", c.Env)
+}
+
+// command checks that a command exits successfully, running it as the owner, if any
+func (c *Condition) command(s Scope, owner string) (Verdict, string) {
+	if s.NoExec {
+		return NotEvaluated, fmt.Sprintf("command '%s' not evaluated", strings.Join(c.Command, " "))
+	}
+	cmd := exec.CommandContext(s.context(), c.Command[0], c.Command[1:]...)
+	if owner != "" {
+		cred, u, err := (&Bin{}).credential(owner)
+		if err != nil {
+			return Unmet, fmt.Sprintf("unable to find user '%s': %s", owner, err)
+		}
+		cmd.SysProcAttr = &syscall.SysProcAttr{Credential: cred}
+		cmd.Env = userEnv(u)
+	}
+	if err := cmd.Run(); err != nil {
+		return Unmet, fmt.Sprintf("command '%s' failed: %s", strings.Join(c.Command, " "), err)
+	}
+	return Met, fmt.Sprintf("command '%s' succeeded", strings.Join(c.Command, " "))
+}
+
+// expect compares a detected state to the wanted one and describes it
+func expect(want, got bool, yes, no string) (Verdict, string) {
+	reason := no
+	if got {
+		reason = yes
+	}
+	if want != got {
+		return Unmet, reason
+	}
+	return Met, reason
+}
+
+// known adapts a check which can always be evaluated
+func known(check func() (bool, string)) func() (Verdict, string) {
+	return func() (Verdict, string) {
+		ok, reason := check()
+		if ok {
+			return Met, reason
+		}
+		return Unmet, reason
+	}
+}
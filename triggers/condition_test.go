@@ -0,0 +1,50 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import "testing"
+
+func TestConditionEvaluate(t *testing.T) {
+	t.Setenv("USYSCONF_TEST_SET", "1")
+	met := Condition{Env: []string{"USYSCONF_TEST_SET"}}
+	unmet := Condition{Env: []string{"USYSCONF_TEST_UNSET"}}
+	skipped := Condition{Command: []string{"false"}}
+	tests := []struct {
+		name string
+		c    Condition
+		want Verdict
+	}{
+		{"met", met, Met},
+		{"unmet", unmet, Unmet},
+		{"command not evaluated", skipped, NotEvaluated},
+		{"not met", Condition{Not: &met}, Unmet},
+		{"not unmet", Condition{Not: &unmet}, Met},
+		{"not of not evaluated", Condition{Not: &skipped}, NotEvaluated},
+		{"any with met", Condition{Any: []Condition{unmet, skipped, met}}, Met},
+		{"any with not evaluated", Condition{Any: []Condition{unmet, skipped}}, NotEvaluated},
+		{"any unmet", Condition{Any: []Condition{unmet, unmet}}, Unmet},
+		{"all met", Condition{All: []Condition{met, met}}, Met},
+		{"all with not evaluated", Condition{All: []Condition{met, skipped}}, NotEvaluated},
+		{"all with unmet", Condition{All: []Condition{skipped, unmet}}, Unmet},
+		{"checks with not evaluated", Condition{Env: met.Env, Command: skipped.Command}, NotEvaluated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, reason := tt.c.Evaluate(Scope{NoExec: true}, ""); got != tt.want {
+				t.Errorf("Evaluate() = %s (%s), want %s", got, reason, tt.want)
+			}
+		})
+	}
+}
@@ -17,7 +17,9 @@ package triggers
 import (
 	"fmt"
 	"github.com/BurntSushi/toml"
+	"io"
 	"io/ioutil"
+	"log/slog"
 	"os"
 	"path/filepath"
 )
@@ -40,12 +42,75 @@ func (t *Trigger) Load(path string) error {
 	return nil
 }
 
+// Decode parses a Trigger configuration from an opened file
+func (t *Trigger) Decode(r io.Reader) error {
+	_, err := toml.NewDecoder(r).Decode(t)
+	return err
+}
+
 // Validate checks for errors in a Trigger configuration
 func (t *Trigger) Validate() error {
-	// Verify that there is at least one binary to execute, otherwise there
+	// Verify that there is at least one action to carry out, otherwise there
 	// is no need to continue
-	if len(t.Bins) == 0 {
-		return fmt.Errorf("triggers must contain at least one [[bin]]")
+	if len(t.Bins) == 0 && len(t.Dirs) == 0 && len(t.Symlinks) == 0 && len(t.Files) == 0 {
+		return fmt.Errorf("triggers must contain at least one [[bins]], [[dirs]], [[symlinks]] or [[files]]")
+	}
+	if err := validatePolicy(t.OnFailure); err != nil {
+		return err
+	}
+	if err := validateRunPolicy(t.RunPolicy); err != nil {
+		return err
+	}
+	if t.Check == nil && t.runPolicy() == OnChange {
+		slog.Warn("Trigger has no [check] and will never run, set a run_policy to run it", "name", t.Name)
+	}
+	if t.Condition != nil {
+		if err := t.Condition.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, b := range t.Bins {
+		if err := validatePolicy(b.OnFailure); err != nil {
+			return fmt.Errorf("bin '%s' has an invalid policy: %w", b.Task, err)
+		}
+		if b.Condition != nil {
+			if err := b.Condition.Validate(); err != nil {
+				return fmt.Errorf("bin '%s' has an invalid condition: %w", b.Task, err)
+			}
+		}
+		if err := b.validateLimits(); err != nil {
+			return fmt.Errorf("bin '%s' has invalid limits: %w", b.Task, err)
+		}
+		if err := b.validateCodes(); err != nil {
+			return fmt.Errorf("bin '%s' has invalid exit rules: %w", b.Task, err)
+		}
+		if b.Retry != nil {
+			if err := b.Retry.Validate(); err != nil {
+				return fmt.Errorf("bin '%s' has an invalid retry policy: %w", b.Task, err)
+			}
+		}
+		if b.Sandbox == nil {
+			continue
+		}
+		if err := b.Sandbox.Validate(); err != nil {
+			return fmt.Errorf("bin '%s' has an invalid [bins.sandbox]: %w", b.Task, err)
+		}
+	}
+	for _, d := range t.Dirs {
+		if err := d.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, l := range t.Symlinks {
+		if err := l.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, f := range t.Files {
+		f.Template = t.template(f.Template)
+		if err := f.Validate(); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -0,0 +1,60 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"github.com/getsolus/usysconf/state"
+)
+
+// Evaluation describes whether a trigger would run, and why. Deleted holds checked
+// paths from the previous state which no longer exist, which don't count as changes.
+// Condition is the outcome of the trigger's condition, if it has one.
+type Evaluation struct {
+	Check     state.Map
+	Diff      state.Map
+	Deleted   state.Map
+	Hash      string
+	Redefined bool
+	Condition *Outcome
+	Skip      bool
+	Reason    string
+}
+
+// Evaluate decides if the trigger would run in this scope, compared to the previous
+// state, without changing anything
+func (t *Trigger) Evaluate(s Scope, prev *state.State) (e Evaluation, err error) {
+	if e.Check, err = t.Check.Scan(); err != nil {
+		return
+	}
+	e.Diff = s.changes(prev.Paths, e.Check)
+	if t.Check != nil {
+		e.Deleted = prev.Paths.Within(t.Check.Paths).Missing(e.Check)
+	}
+	if e.Hash, err = t.Hash(); err != nil {
+		return
+	}
+	e.Redefined = prev.Redefined(t.Name, e.Hash)
+	if t.Condition != nil {
+		e.Condition = t.Condition.Outcome(s, t.Owner)
+	}
+	t.Decide(s, prev, &e)
+	return
+}
+
+// Decide works out whether an evaluated trigger would run in this scope, reusing the
+// outcome of its condition, so decisions in different scopes can be compared cheaply
+func (t *Trigger) Decide(s Scope, prev *state.State, e *Evaluation) {
+	e.Skip, e.Reason = t.skipReason(s, prev, e)
+}
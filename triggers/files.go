@@ -0,0 +1,299 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"text/template"
+)
+
+// Dir contains the details of a directory to be created.
+type Dir struct {
+	Path  string `toml:"path"`
+	Mode  string `toml:"mode,omitempty"`
+	Owner string `toml:"owner,omitempty"`
+	Group string `toml:"group,omitempty"`
+}
+
+// Symlink contains the details of a symbolic link to be created or updated.
+type Symlink struct {
+	Path   string `toml:"path"`
+	Target string `toml:"target"`
+}
+
+// File contains the details of a file to be written, either from inline content or
+// from a template which is rendered with the trigger name and environment. Templates
+// are found relative to the trigger file.
+type File struct {
+	Path     string `toml:"path"`
+	Content  string `toml:"content,omitempty"`
+	Template string `toml:"template,omitempty"`
+	Mode     string `toml:"mode,omitempty"`
+	Owner    string `toml:"owner,omitempty"`
+	Group    string `toml:"group,omitempty"`
+}
+
+// templateData is made available to File templates
+type templateData struct {
+	Name string
+	Env  map[string]string
+}
+
+// CreateFiles creates all of the directories, symlinks and files for the trigger, in that order
+func (t *Trigger) CreateFiles(s Scope) bool {
+	if len(t.Dirs) == 0 && len(t.Symlinks) == 0 && len(t.Files) == 0 {
+		slog.Debug("No files to create")
+		return true
+	}
+	for _, d := range t.Dirs {
+		if !t.report(s, "Creating directory", d.Path, d.Apply) {
+			return false
+		}
+	}
+	for _, l := range t.Symlinks {
+		if !t.report(s, "Linking", l.Path, l.Apply) {
+			return false
+		}
+	}
+	for _, f := range t.Files {
+		f.Template = t.template(f.Template)
+		apply := func() error {
+			return f.Apply(t.Name, t.Env)
+		}
+		if !t.report(s, "Writing file", f.Path, apply) {
+			return false
+		}
+	}
+	return true
+}
+
+// template finds a template, which is relative to the trigger file unless absolute
+func (t *Trigger) template(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(t.Path), path)
+}
+
+// report runs a single action, unless this is a dry-run, and records its Output
+func (t *Trigger) report(s Scope, task, path string, apply func() error) bool {
+	out := Output{Name: task, SubTask: path, Status: Success}
+	if s.DryRun {
+		s.planFile(task, path)
+	} else if err := apply(); err != nil {
+		out.Status = Failure
+		out.Message = fmt.Sprintf("%s '%s' failed, reason: %s", task, path, err)
+	}
+	t.Output = append(t.Output, out)
+	return out.Status != Failure
+}
+
+// Validate checks for errors in a Dir
+func (d Dir) Validate() error {
+	if !filepath.IsAbs(d.Path) {
+		return fmt.Errorf("[[dirs]] path '%s' must be absolute", d.Path)
+	}
+	_, err := parseMode(d.Mode, 0755)
+	return err
+}
+
+// Apply creates the directory and sets its permissions and ownership. An existing link
+// is refused, rather than changing whatever it points to.
+func (d Dir) Apply() error {
+	mode, err := parseMode(d.Mode, 0755)
+	if err != nil {
+		return err
+	}
+	if err = notLink(d.Path); err != nil {
+		return err
+	}
+	if err = os.MkdirAll(d.Path, 0755); err != nil {
+		return err
+	}
+	if err = os.Chmod(d.Path, mode); err != nil {
+		return err
+	}
+	return chown(d.Path, d.Owner, d.Group)
+}
+
+// Validate checks for errors in a Symlink
+func (l Symlink) Validate() error {
+	if !filepath.IsAbs(l.Path) {
+		return fmt.Errorf("[[symlinks]] path '%s' must be absolute", l.Path)
+	}
+	if l.Target == "" {
+		return fmt.Errorf("[[symlinks]] '%s' must have a target", l.Path)
+	}
+	return nil
+}
+
+// Apply creates the link, or replaces an existing link with a different target
+func (l Symlink) Apply() error {
+	info, err := os.Lstat(l.Path)
+	switch {
+	case os.IsNotExist(err):
+		if err = os.MkdirAll(filepath.Dir(l.Path), 0755); err != nil {
+			return err
+		}
+		return os.Symlink(l.Target, l.Path)
+	case err != nil:
+		return err
+	case info.Mode()&os.ModeSymlink == 0:
+		return fmt.Errorf("refusing to replace non-link")
+	}
+	if current, err := os.Readlink(l.Path); err == nil && current == l.Target {
+		return nil
+	}
+	tmp := l.Path + ".usysconf-new"
+	_ = os.Remove(tmp)
+	if err = os.Symlink(l.Target, tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.Path)
+}
+
+// Validate checks for errors in a File
+func (f File) Validate() error {
+	if !filepath.IsAbs(f.Path) {
+		return fmt.Errorf("[[files]] path '%s' must be absolute", f.Path)
+	}
+	if f.Content != "" && f.Template != "" {
+		return fmt.Errorf("[[files]] '%s' cannot have both content and a template", f.Path)
+	}
+	if f.Template != "" {
+		if _, err := template.ParseFiles(f.Template); err != nil {
+			return fmt.Errorf("[[files]] '%s' has a bad template: %w", f.Path, err)
+		}
+	}
+	_, err := parseMode(f.Mode, 0644)
+	return err
+}
+
+// Render gets the contents that should be written to the File
+func (f File) Render(name string, env map[string]string) ([]byte, error) {
+	if f.Template == "" {
+		return []byte(f.Content), nil
+	}
+	tmpl, err := template.ParseFiles(f.Template)
+	if err != nil {
+		return nil, err
+	}
+	var buff bytes.Buffer
+	if err = tmpl.Execute(&buff, templateData{Name: name, Env: env}); err != nil {
+		return nil, err
+	}
+	return buff.Bytes(), nil
+}
+
+// Apply writes the file if its contents changed, then sets its permissions and ownership.
+// An existing link is refused, rather than changing whatever it points to.
+func (f File) Apply(name string, env map[string]string) error {
+	mode, err := parseMode(f.Mode, 0644)
+	if err != nil {
+		return err
+	}
+	if err = notLink(f.Path); err != nil {
+		return err
+	}
+	content, err := f.Render(name, env)
+	if err != nil {
+		return err
+	}
+	if current, err := os.ReadFile(f.Path); err != nil || !bytes.Equal(current, content) {
+		if err = os.MkdirAll(filepath.Dir(f.Path), 0755); err != nil {
+			return err
+		}
+		tmp := f.Path + ".usysconf-new"
+		if err = os.WriteFile(tmp, content, mode); err != nil {
+			return err
+		}
+		if err = os.Rename(tmp, f.Path); err != nil {
+			_ = os.Remove(tmp)
+			return err
+		}
+	}
+	if err = os.Chmod(f.Path, mode); err != nil {
+		return err
+	}
+	return chown(f.Path, f.Owner, f.Group)
+}
+
+// notLink refuses a path which is a symbolic link, allowing one which doesn't exist yet
+func notLink(path string) error {
+	info, err := os.Lstat(path)
+	switch {
+	case os.IsNotExist(err):
+		return nil
+	case err != nil:
+		return err
+	case info.Mode()&os.ModeSymlink != 0:
+		return fmt.Errorf("refusing to follow link")
+	}
+	return nil
+}
+
+// specialModes maps the setuid, setgid and sticky bits of an octal mode to their FileMode
+var specialModes = map[uint64]os.FileMode{
+	04000: os.ModeSetuid,
+	02000: os.ModeSetgid,
+	01000: os.ModeSticky,
+}
+
+// parseMode reads an octal permission string, using a fallback when empty
+func parseMode(mode string, fallback os.FileMode) (os.FileMode, error) {
+	if mode == "" {
+		return fallback, nil
+	}
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || m > 07777 {
+		return 0, fmt.Errorf("invalid mode '%s'", mode)
+	}
+	perm := os.FileMode(m & 0777)
+	for bit, special := range specialModes {
+		if m&bit != 0 {
+			perm |= special
+		}
+	}
+	return perm, nil
+}
+
+// chown changes the ownership of a path, if an owner or group was specified
+func chown(path, owner, group string) error {
+	if owner == "" && group == "" {
+		return nil
+	}
+	uid, gid := -1, -1
+	if owner != "" {
+		u, err := user.Lookup(owner)
+		if err != nil {
+			return err
+		}
+		uid, _ = strconv.Atoi(u.Uid)
+	}
+	if group != "" {
+		g, err := user.LookupGroup(group)
+		if err != nil {
+			return err
+		}
+		gid, _ = strconv.Atoi(g.Gid)
+	}
+	return os.Lchown(path, uid, gid)
+}
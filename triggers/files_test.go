@@ -0,0 +1,83 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		mode    string
+		want    os.FileMode
+		invalid bool
+	}{
+		{"", 0644, false},
+		{"0755", 0755, false},
+		{"4755", os.ModeSetuid | 0755, false},
+		{"2775", os.ModeSetgid | 0775, false},
+		{"1777", os.ModeSticky | 0777, false},
+		{"6755", os.ModeSetuid | os.ModeSetgid | 0755, false},
+		{"10000", 0, true},
+		{"0789", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseMode(tt.mode, 0644)
+		if (err != nil) != tt.invalid {
+			t.Errorf("parseMode(%q) error = %v, want invalid %v", tt.mode, err, tt.invalid)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseMode(%q) = %v, want %v", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestApplyRefusesLinks(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	if err := os.WriteFile(target, []byte("keep"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := (File{Path: link, Content: "keep", Mode: "0666"}).Apply("test", nil); err == nil {
+		t.Error("File.Apply() followed a link")
+	}
+	if err := (Dir{Path: link, Mode: "0777"}).Apply(); err == nil {
+		t.Error("Dir.Apply() followed a link")
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("target mode = %v, want 0600", info.Mode().Perm())
+	}
+	sub := filepath.Join(dir, "sub")
+	if err := (Dir{Path: sub, Mode: "1777"}).Apply(); err != nil {
+		t.Fatalf("Dir.Apply() error = %v", err)
+	}
+	if info, err = os.Stat(sub); err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSticky == 0 {
+		t.Errorf("Dir.Apply() mode = %v, want sticky", info.Mode())
+	}
+}
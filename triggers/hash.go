@@ -0,0 +1,36 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Hash identifies the effective definition of a trigger, after any overrides, so
+// that changes to it can be detected between runs
+func (t *Trigger) Hash() (string, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "owner = %q\n", t.Owner)
+	if err := toml.NewEncoder(&buf).Encode(t); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,287 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/getsolus/usysconf/sandbox"
+)
+
+// CgroupRoot is the mount point of the unified cgroup hierarchy
+var CgroupRoot = "/sys/fs/cgroup"
+
+// backgroundNice is the niceness used by "run --background"
+const backgroundNice = 19
+
+// cgroup2Magic identifies a cgroup v2 filesystem
+const cgroup2Magic = 0x63677270
+
+// ioprioClassShift positions the class within an I/O priority, and is missing from the syscall package
+const ioprioClassShift = 13
+
+// ioClasses maps the names of I/O scheduling classes to their numbers
+var ioClasses = map[string]int{
+	"realtime":    1,
+	"best-effort": 2,
+	"idle":        3,
+}
+
+// IONice contains the I/O scheduling class and level for a binary.
+type IONice struct {
+	Class string `toml:"class"`
+	Level int    `toml:"level,omitempty"`
+}
+
+// Rlimit contains resource limits for a binary. Zero values are left unlimited.
+type Rlimit struct {
+	AddressSpace uint64 `toml:"address_space,omitempty"`
+	OpenFiles    uint64 `toml:"open_files,omitempty"`
+	CPUTime      uint64 `toml:"cpu_time,omitempty"`
+}
+
+// Cgroup contains the limits for a cgroup v2 sub-group created for a binary.
+// MemoryMax accepts the kernel's K, M and G suffixes, and CPUMax accepts either a
+// percentage of one CPU or the raw "quota period" form.
+type Cgroup struct {
+	MemoryMax string `toml:"memory_max,omitempty"`
+	CPUMax    string `toml:"cpu_max,omitempty"`
+}
+
+// validateLimits checks for errors in the resource limits of a Bin
+func (b *Bin) validateLimits() error {
+	if b.Nice != nil && (*b.Nice < -20 || *b.Nice > 19) {
+		return fmt.Errorf("nice must be between -20 and 19")
+	}
+	if b.IONice != nil {
+		if _, ok := ioClasses[b.IONice.Class]; !ok {
+			return fmt.Errorf("unknown ionice class '%s'", b.IONice.Class)
+		}
+		if b.IONice.Level < 0 || b.IONice.Level > 7 {
+			return fmt.Errorf("ionice level must be between 0 and 7")
+		}
+	}
+	if b.Cgroup != nil {
+		if _, err := cpuMax(b.Cgroup.CPUMax); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// priorities gets the niceness and I/O priority for the bin, using the background
+// preset for anything that wasn't specified
+func (b *Bin) priorities(s Scope) (nice *int, io *IONice) {
+	nice, io = b.Nice, b.IONice
+	if s.Background {
+		if nice == nil {
+			n := backgroundNice
+			nice = &n
+		}
+		if io == nil {
+			io = &IONice{Class: "idle"}
+		}
+	}
+	return
+}
+
+// limits gets the priorities and resource limits for the bin, if any
+func (b *Bin) limits(s Scope) *sandbox.Limits {
+	var l sandbox.Limits
+	nice, io := b.priorities(s)
+	l.Nice = nice
+	if io != nil {
+		prio := ioClasses[io.Class]<<ioprioClassShift | io.Level
+		l.IOPrio = &prio
+	}
+	if b.Rlimit != nil {
+		l.Rlimits = make(map[int]uint64)
+		for resource, value := range map[int]uint64{
+			syscall.RLIMIT_AS:     b.Rlimit.AddressSpace,
+			syscall.RLIMIT_NOFILE: b.Rlimit.OpenFiles,
+			syscall.RLIMIT_CPU:    b.Rlimit.CPUTime,
+		} {
+			if value != 0 {
+				l.Rlimits[resource] = value
+			}
+		}
+	}
+	if l.Nice == nil && l.IOPrio == nil && len(l.Rlimits) == 0 {
+		return nil
+	}
+	return &l
+}
+
+// ownCgroup finds the cgroup this process runs in, within the unified hierarchy
+func ownCgroup() (string, error) {
+	raw, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(raw), "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			return filepath.Join(CgroupRoot, path), nil
+		}
+	}
+	return "", fmt.Errorf("not in a unified cgroup hierarchy")
+}
+
+// cgroupParent gets the cgroup holding the cgroups of every bin in this run, below the
+// cgroup of this process
+func (s Scope) cgroupParent() (string, error) {
+	own, err := ownCgroup()
+	if err != nil {
+		return "", err
+	}
+	id := s.ID
+	if id == "" {
+		id = strconv.Itoa(os.Getpid())
+	}
+	return filepath.Join(own, "usysconf-"+id), nil
+}
+
+// removeCgroup removes the cgroup holding the cgroups of this run, if it was created
+func (s Scope) removeCgroup() {
+	parent, err := s.cgroupParent()
+	if err != nil {
+		return
+	}
+	if err := os.Remove(parent); err != nil && !os.IsNotExist(err) {
+		slog.Debug("Failed to remove cgroup", "path", parent, "reason", err)
+	}
+}
+
+// controllers lists the cgroup controllers needed for the limits
+func (c *Cgroup) controllers() (names []string) {
+	if c.MemoryMax != "" {
+		names = append(names, "memory")
+	}
+	if c.CPUMax != "" {
+		names = append(names, "cpu")
+	}
+	return
+}
+
+// attachCgroup creates a cgroup for the command and starts it inside of it, returning
+// a function to remove the cgroup once the command is done, and whether it was attached.
+// The cgroup is created below the cgroup of this process, in a cgroup for the run, so
+// the controllers must already be enabled there. When the cgroup can't be set up, such
+// as in a container, the command runs without it.
+func (b *Bin) attachCgroup(s Scope, cmd *exec.Cmd, label string) (cleanup func(), attached bool) {
+	cleanup = func() {}
+	if b.Cgroup == nil {
+		return
+	}
+	logger := slog.With("bin", b.Bin)
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(CgroupRoot, &fs); err != nil || fs.Type != cgroup2Magic {
+		logger.Warn("Unified cgroup hierarchy is unavailable, running without cgroup limits")
+		return
+	}
+	parent, err := s.cgroupParent()
+	if err == nil {
+		err = os.Mkdir(parent, 0755)
+		if os.IsExist(err) {
+			err = nil
+		}
+	}
+	if err != nil {
+		logger.Warn("Failed to create cgroup, running without cgroup limits", "reason", err)
+		return
+	}
+	// Only the cgroup of the run is ours to enable controllers in
+	var enable []string
+	for _, name := range b.Cgroup.controllers() {
+		enable = append(enable, "+"+name)
+	}
+	if len(enable) > 0 {
+		err = os.WriteFile(filepath.Join(parent, "cgroup.subtree_control"), []byte(strings.Join(enable, " ")), 0644)
+		if err != nil {
+			logger.Warn("Cgroup controllers are unavailable, running without cgroup limits", "cgroup", filepath.Dir(parent), "reason", err)
+			return
+		}
+	}
+	name := strings.NewReplacer("/", "-", " ", "_").Replace(label)
+	dir := filepath.Join(parent, name)
+	if err := os.Mkdir(dir, 0755); err != nil && !os.IsExist(err) {
+		logger.Warn("Failed to create cgroup, running without cgroup limits", "reason", err)
+		return
+	}
+	remove := func() {
+		if err := os.Remove(dir); err != nil {
+			slog.Debug("Failed to remove cgroup", "path", dir, "reason", err)
+		}
+	}
+	if b.Cgroup.MemoryMax != "" {
+		err = os.WriteFile(filepath.Join(dir, "memory.max"), []byte(b.Cgroup.MemoryMax), 0644)
+	}
+	if err == nil && b.Cgroup.CPUMax != "" {
+		value, _ := cpuMax(b.Cgroup.CPUMax)
+		err = os.WriteFile(filepath.Join(dir, "cpu.max"), []byte(value), 0644)
+	}
+	var fd int
+	if err == nil {
+		fd, err = syscall.Open(dir, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_CLOEXEC, 0)
+	}
+	if err != nil {
+		logger.Warn("Failed to set up cgroup, running without cgroup limits", "reason", err)
+		remove()
+		return
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = fd
+	cleanup = func() {
+		_ = syscall.Close(fd)
+		remove()
+	}
+	return cleanup, true
+}
+
+// cpuMax converts a CPU limit, either a percentage of one CPU or the "quota period" form,
+// into the form used by cpu.max
+func cpuMax(value string) (string, error) {
+	if value == "" {
+		return value, nil
+	}
+	if !strings.HasSuffix(value, "%") {
+		fields := strings.Fields(value)
+		if len(fields) == 2 && (fields[0] == "max" || positive(fields[0])) && positive(fields[1]) {
+			return value, nil
+		}
+		return "", fmt.Errorf("invalid cpu_max '%s', expected a percentage or \"quota period\"", value)
+	}
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil || percent <= 0 {
+		return "", fmt.Errorf("invalid cpu_max '%s'", value)
+	}
+	const period = 100000
+	return fmt.Sprintf("%d %d", int(percent*period/100), period), nil
+}
+
+// positive checks if a string is a whole number above zero
+func positive(value string) bool {
+	n, err := strconv.ParseUint(value, 10, 64)
+	return err == nil && n > 0
+}
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"log/slog"
 	"sort"
+	"strings"
 
 	"github.com/getsolus/usysconf/deps"
 	"github.com/getsolus/usysconf/state"
@@ -33,8 +34,8 @@ func (tm Map) Merge(tm2 Map) {
 	}
 }
 
-// Print renders a Map in a human-readable format
-func (tm Map) Print(chroot, live bool) {
+// Print renders a Map in a human-readable format, leaving out triggers skipped in this scope
+func (tm Map) Print(s Scope) {
 	var keys []string
 	max := 0
 	for k := range tm {
@@ -45,53 +46,104 @@ func (tm Map) Print(chroot, live bool) {
 	}
 	max += 4
 	sort.Strings(keys)
-	f := fmt.Sprintf("%%%ds - %%s\n", max)
+	f := fmt.Sprintf("%%%ds - %%s%%s\n", max)
 	for _, key := range keys {
 		t := tm[key]
-		if t.Skip != nil {
-			if (t.Skip.Chroot && chroot) || (t.Skip.Live && live) {
-				continue
-			}
+		if skip, _ := t.Skip.Matches(s); skip {
+			continue
+		}
+		tags := ""
+		if len(t.Tags) > 0 {
+			tags = " [" + strings.Join(t.Tags, ", ") + "]"
 		}
-		fmt.Printf(f, t.Name, t.Description)
+		fmt.Printf(f, t.Name, t.Description, tags)
 	}
 	fmt.Println()
 }
 
-// Graph generates a dependency graph
-func (tm Map) Graph(chroot, live bool) (g deps.Graph) {
+// Graph generates a dependency graph, leaving out triggers skipped in this scope
+func (tm Map) Graph(s Scope) (g deps.Graph) {
 	g = make(deps.Graph)
 	var names []string
 	for _, t := range tm {
-		if t.Skip != nil {
-			if (t.Skip.Chroot && chroot) || (t.Skip.Live && live) {
-				continue
-			}
+		names = append(names, t.Name)
+		if skip, _ := t.Skip.Matches(s); skip {
+			continue
 		}
 		if t.Deps != nil {
 			g.Insert(t.Name, t.Deps.After)
 		}
-		names = append(names, t.Name)
 	}
 	g.Validate(names)
 	return
 }
 
-// Run executes a list of triggers, where available
-func (tm Map) Run(s Scope, names []string) {
-	prev, err := state.Load()
+// Nodes describes every trigger not skipped in this scope, for exporting a Graph
+func (tm Map) Nodes(s Scope) map[string]deps.Node {
+	nodes := make(map[string]deps.Node)
+	for _, t := range tm {
+		if skip, _ := t.Skip.Matches(s); skip {
+			continue
+		}
+		node := deps.Node{Name: t.Name, Description: t.Description}
+		if sk := t.Skip; sk != nil {
+			for _, flag := range []struct {
+				set  bool
+				name string
+			}{
+				{sk.Chroot, "chroot"},
+				{sk.Live, "live"},
+				{sk.Container, "container"},
+				{sk.Virt, "virt"},
+				{len(sk.Paths) > 0, "paths"},
+			} {
+				if flag.set {
+					node.Skip = append(node.Skip, flag.name)
+				}
+			}
+		}
+		nodes[t.Name] = node
+	}
+	return nodes
+}
+
+// Run executes a list of triggers, where available, and reports their results
+func (tm Map) Run(s Scope, names []string) (results []Result) {
+	saved, err := state.Load()
 
 	if err != nil {
 		slog.Error("Failed to read state file", "reason", err)
 		return
 	}
 
-	next := make(state.Map)
+	// Start from the saved state, so that triggers which don't run keep theirs
+	next := saved.Copy()
+	// Compare against the saved state, unless asked to use another
+	prev := saved
+	if s.Baseline != nil {
+		prev = s.Baseline
+	}
 	// Resolve deps
-	g := tm.Graph(s.Chroot, s.Live)
+	g := tm.Graph(s)
 	order := g.Resolve(names)
 	// Iterate over triggers
+	stopped := ""
 	for _, name := range order {
+		// Stop if the run was cancelled or timed out
+		if err := s.context().Err(); err != nil {
+			slog.Error("Run interrupted, not running remaining triggers", "reason", err)
+			break
+		}
+		// Skip everything else if a trigger asked to stop the run
+		if stopped != "" {
+			r := Result{Name: name, Status: Skipped, Message: fmt.Sprintf("not run because '%s' failed", stopped)}
+			s.planTrigger(name, false, r.Message)
+			slog.Debug(name, "trigger", name, "status", r.Status, "reason", r.Message)
+			s.start(name)
+			s.finish(r)
+			results = append(results, r)
+			continue
+		}
 		// Get Trigger if available
 		t, ok := tm[name]
 		if !ok {
@@ -99,7 +151,20 @@ func (tm Map) Run(s Scope, names []string) {
 			continue
 		}
 		// Run Trigger
-		t.Run(s, prev, next)
+		s.start(name)
+		r := t.Run(s, prev, next)
+		s.finish(r)
+		results = append(results, r)
+		if r.Abort {
+			slog.Error("Stopping run after failure", "trigger", name)
+			stopped = name
+		}
+	}
+	// Clean up after backups, leaving anything which could not be restored
+	s.removeStaging()
+	s.removeCgroup()
+	if s.Plan != nil {
+		s.Plan.State = saved.Paths.Diff(next.Paths)
 	}
 	if !s.DryRun {
 		// Save new State for next run
@@ -107,4 +172,5 @@ func (tm Map) Run(s Scope, names []string) {
 			slog.Error("Failed to save next state file", "reason", err)
 		}
 	}
+	return
 }
@@ -16,8 +16,11 @@ package triggers
 
 // Output contains the details necessary to output the configuration details to the user.
 type Output struct {
-	Name    string
-	SubTask string
-	Message string
-	Status  Status
+	Name     string
+	SubTask  string
+	Message  string
+	Captured string
+	Code     int
+	Status   Status
+	Attempts []Attempt
 }
@@ -0,0 +1,134 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/getsolus/usysconf/state"
+)
+
+// Plan records everything a dry-run would have done, in order
+type Plan struct {
+	Triggers []PlannedTrigger `json:"triggers"`
+	State    state.Map        `json:"state"`
+}
+
+// PlannedTrigger is what a single trigger would have done, and why
+type PlannedTrigger struct {
+	Name     string           `json:"name"`
+	Run      bool             `json:"run"`
+	Reason   string           `json:"reason"`
+	Removals []string         `json:"removals,omitempty"`
+	Files    []PlannedFile    `json:"files,omitempty"`
+	Commands []PlannedCommand `json:"commands,omitempty"`
+}
+
+// PlannedFile is a directory, symlink or file which would have been created
+type PlannedFile struct {
+	Action string `json:"action"`
+	Path   string `json:"path"`
+}
+
+// PlannedCommand is a binary which would have been executed, or left out and why
+type PlannedCommand struct {
+	Label   string   `json:"label"`
+	Argv    []string `json:"argv,omitempty"`
+	Env     []string `json:"env,omitempty"`
+	User    string   `json:"user,omitempty"`
+	Skipped string   `json:"skipped,omitempty"`
+}
+
+// planTrigger records the decision for a trigger in the Plan, if any
+func (s Scope) planTrigger(name string, run bool, reason string) {
+	if s.Plan != nil {
+		s.Plan.Triggers = append(s.Plan.Triggers, PlannedTrigger{Name: name, Run: run, Reason: reason})
+	}
+}
+
+// planned gets the trigger currently being planned, if any
+func (s Scope) planned() *PlannedTrigger {
+	if s.Plan == nil || len(s.Plan.Triggers) == 0 {
+		return nil
+	}
+	return &s.Plan.Triggers[len(s.Plan.Triggers)-1]
+}
+
+// planRemoval records a path which would have been removed
+func (s Scope) planRemoval(path string) {
+	if p := s.planned(); p != nil {
+		p.Removals = append(p.Removals, path)
+	}
+}
+
+// planFile records a directory, symlink or file which would have been created
+func (s Scope) planFile(action, path string) {
+	if p := s.planned(); p != nil {
+		p.Files = append(p.Files, PlannedFile{Action: action, Path: path})
+	}
+}
+
+// planCommand records a binary which would have been executed
+func (s Scope) planCommand(cmd PlannedCommand) {
+	if p := s.planned(); p != nil {
+		p.Commands = append(p.Commands, cmd)
+	}
+}
+
+// planSkipped records a bin which would not have been executed, and why
+func (s Scope) planSkipped(label, reason string) {
+	if p := s.planned(); p != nil {
+		p.Commands = append(p.Commands, PlannedCommand{Label: label, Skipped: reason})
+	}
+}
+
+// Print renders a Plan in a human-readable format
+func (p *Plan) Print(w io.Writer) {
+	for _, t := range p.Triggers {
+		if !t.Run {
+			fmt.Fprintf(w, "%s: skip, %s\n", t.Name, t.Reason)
+			continue
+		}
+		fmt.Fprintf(w, "%s: run, %s\n", t.Name, t.Reason)
+		for _, path := range t.Removals {
+			fmt.Fprintf(w, "    remove %s\n", path)
+		}
+		for _, f := range t.Files {
+			fmt.Fprintf(w, "    %s %s\n", strings.ToLower(f.Action), f.Path)
+		}
+		for _, cmd := range t.Commands {
+			if cmd.Skipped != "" {
+				fmt.Fprintf(w, "    skip [%s] %s\n", cmd.Label, cmd.Skipped)
+				continue
+			}
+			fmt.Fprintf(w, "    exec [%s] %s\n", cmd.Label, strings.Join(cmd.Argv, " "))
+			if cmd.User != "" {
+				fmt.Fprintf(w, "        user %s\n", cmd.User)
+			}
+			for _, env := range cmd.Env {
+				fmt.Fprintf(w, "        env %s\n", env)
+			}
+		}
+	}
+	paths := p.State.Strings()
+	sort.Strings(paths)
+	fmt.Fprintf(w, "\nState changes: %d\n", len(paths))
+	for _, path := range paths {
+		fmt.Fprintf(w, "    %s %s\n", p.State[path].Format("2006-01-02 15:04:05"), path)
+	}
+}
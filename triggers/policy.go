@@ -0,0 +1,49 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"fmt"
+)
+
+const (
+	// Continue - Keep going after a failure.
+	Continue = "continue"
+	// StopTrigger - Skip the remaining bins of the trigger after a failure.
+	StopTrigger = "stop-trigger"
+	// StopRun - Skip the remaining bins and triggers after a failure.
+	StopRun = "stop-run"
+)
+
+// validatePolicy checks that a failure policy is one of the known values
+func validatePolicy(policy string) error {
+	switch policy {
+	case "", Continue, StopTrigger, StopRun:
+		return nil
+	default:
+		return fmt.Errorf("unknown on_failure policy '%s'", policy)
+	}
+}
+
+// policy gets the failure policy for a bin, falling back to the one for the trigger
+func (t *Trigger) policy(b Bin) string {
+	if b.OnFailure != "" {
+		return b.OnFailure
+	}
+	if t.OnFailure != "" {
+		return t.OnFailure
+	}
+	return Continue
+}
@@ -15,17 +15,25 @@
 package triggers
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"sort"
+	"syscall"
 
 	"github.com/getsolus/usysconf/state"
 )
 
 // Remove contains paths to be removed from the system. This supports globbing.
+// Recursive removals delete the contents of directories before the directories
+// themselves, keeping any directory that still holds excluded files. Backups move
+// the matches aside so that they can be restored if the trigger fails.
 type Remove struct {
-	Paths   []string `toml:"paths"`
-	Exclude []string `toml:"exclude"`
+	Paths     []string `toml:"paths"`
+	Exclude   []string `toml:"exclude"`
+	Recursive bool     `toml:"recursive,omitempty"`
+	Backup    bool     `toml:"backup,omitempty"`
 }
 
 // Remove glob the paths and if it exists it will remove it from the system
@@ -45,9 +53,25 @@ func (t *Trigger) Remove(s Scope) bool {
 	return true
 }
 
+// Targets finds the paths to remove, in the order they will be removed
+func (r Remove) Targets() ([]string, error) {
+	matches, err := state.Scan(r.Paths)
+	if err != nil {
+		return nil, err
+	}
+	paths := matches.Exclude(r.Exclude).Strings()
+	if r.Recursive {
+		// Reverse order puts the contents of a directory ahead of it
+		sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+	} else {
+		sort.Strings(paths)
+	}
+	return paths, nil
+}
+
 // removeOne carries out removals for a single Remove entry
 func (t *Trigger) removeOne(s Scope, remove Remove) bool {
-	matches, err := state.Scan(remove.Paths)
+	paths, err := remove.Targets()
 	if err != nil {
 		out := Output{
 			Status:  Failure,
@@ -56,13 +80,22 @@ func (t *Trigger) removeOne(s Scope, remove Remove) bool {
 		t.Output = append(t.Output, out)
 		return false
 	}
-	matches = matches.Exclude(remove.Exclude)
-	for path := range matches {
+	for _, path := range paths {
 		slog.Debug("Removing", "path", path)
 		if s.DryRun {
+			s.planRemoval(path)
 			continue
 		}
-		if err := os.Remove(path); err != nil {
+		if remove.Backup {
+			err = t.stage(s, path)
+		} else {
+			err = os.Remove(path)
+		}
+		if err != nil {
+			if remove.Recursive && errors.Is(err, syscall.ENOTEMPTY) {
+				slog.Debug("Keeping non-empty directory", "path", path)
+				continue
+			}
 			out := Output{
 				Status:  Failure,
 				Message: fmt.Sprintf("Failed to remove path '%s', reason: %s\n", path, err),
@@ -0,0 +1,46 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+// Reporter receives progress updates while triggers are being run
+type Reporter interface {
+	// Start is called before a trigger is run
+	Start(name string)
+	// Progress is called after each of the binaries for a trigger has been executed
+	Progress(name string, done, total int)
+	// Finish is called with the outcome of a trigger
+	Finish(r Result)
+}
+
+// start notifies the Reporter, if any, that a trigger is about to run
+func (s Scope) start(name string) {
+	if s.Reporter != nil {
+		s.Reporter.Start(name)
+	}
+}
+
+// progress notifies the Reporter, if any, of completed binaries
+func (s Scope) progress(name string, done, total int) {
+	if s.Reporter != nil {
+		s.Reporter.Progress(name, done, total)
+	}
+}
+
+// finish notifies the Reporter, if any, that a trigger is done
+func (s Scope) finish(r Result) {
+	if s.Reporter != nil {
+		s.Reporter.Finish(r)
+	}
+}
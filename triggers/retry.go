@@ -0,0 +1,108 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Retry contains the details for re-running a binary that failed. The delay between
+// attempts is multiplied by the backoff after each one. When OnCodes is set, only
+// failures with one of those exit codes are retried.
+type Retry struct {
+	Attempts int           `toml:"attempts"`
+	Delay    time.Duration `toml:"delay,omitempty"`
+	Backoff  float64       `toml:"backoff,omitempty"`
+	OnCodes  []int         `toml:"on_codes,omitempty"`
+}
+
+// Attempt is the outcome of a single try at running a binary
+type Attempt struct {
+	Status   Status
+	Code     int
+	Message  string
+	Duration time.Duration
+}
+
+// Validate checks for errors in a Retry
+func (r *Retry) Validate() error {
+	if r.Attempts < 1 {
+		return fmt.Errorf("[bins.retry] attempts must be at least 1")
+	}
+	if r.Delay < 0 {
+		return fmt.Errorf("[bins.retry] delay cannot be negative")
+	}
+	if r.Backoff != 0 && r.Backoff < 1 {
+		return fmt.Errorf("[bins.retry] backoff must be at least 1")
+	}
+	return nil
+}
+
+// attempts gets the maximum number of times a binary should be tried
+func (r *Retry) attempts() int {
+	if r == nil {
+		return 1
+	}
+	return r.Attempts
+}
+
+// allows checks if a failure with the provided exit code should be retried
+func (r *Retry) allows(code int) bool {
+	if r == nil {
+		return false
+	}
+	if len(r.OnCodes) == 0 {
+		return true
+	}
+	for _, c := range r.OnCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// next gets the delay to use after the current one
+func (r *Retry) next(delay time.Duration) time.Duration {
+	if r.Backoff <= 1 {
+		return delay
+	}
+	return time.Duration(float64(delay) * r.Backoff)
+}
+
+// context gets the context used to cancel or time out a run
+func (s Scope) context() context.Context {
+	if s.Context == nil {
+		return context.Background()
+	}
+	return s.Context
+}
+
+// wait pauses for a delay, returning early with an error if the run is cancelled
+func (s Scope) wait(delay time.Duration) error {
+	if delay <= 0 {
+		return s.context().Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-s.context().Done():
+		return s.context().Err()
+	}
+}
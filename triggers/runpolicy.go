@@ -0,0 +1,55 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"fmt"
+)
+
+const (
+	// OnChange - Run when the checked paths, or the trigger itself, change.
+	OnChange = "on-change"
+	// Always - Run every time, unless skipped.
+	Always = "always"
+	// Once - Run until it has succeeded once.
+	Once = "once"
+	// Manual - Only run when asked for by name.
+	Manual = "manual"
+)
+
+// policies describes why a trigger with each run policy, other than OnChange, runs
+var policies = map[string]string{
+	Always: "runs every time",
+	Once:   "has not run yet",
+	Manual: "only runs when asked for by name",
+}
+
+// validateRunPolicy checks that a run policy is one of the known values
+func validateRunPolicy(policy string) error {
+	switch policy {
+	case "", OnChange, Always, Once, Manual:
+		return nil
+	default:
+		return fmt.Errorf("unknown run_policy '%s'", policy)
+	}
+}
+
+// runPolicy gets the run policy of the trigger, which defaults to OnChange
+func (t *Trigger) runPolicy() string {
+	if t.RunPolicy == "" {
+		return OnChange
+	}
+	return t.RunPolicy
+}
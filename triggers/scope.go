@@ -14,11 +14,46 @@
 
 package triggers
 
+import (
+	"context"
+	"time"
+
+	"github.com/getsolus/usysconf/state"
+)
+
 // Scope sets limits of execution for a trigger
 type Scope struct {
+	Context context.Context
+
+	ID     string
 	Chroot bool
 	Debug  bool
 	DryRun bool
 	Forced bool
 	Live   bool
+	Stream bool
+	// NoExec decides without running command conditions, which may have side effects
+	NoExec bool
+
+	Container string
+	Virt      string
+
+	Background bool
+
+	// Since treats every checked path modified after it as changed, when set
+	Since time.Time
+	// Baseline is compared against instead of the saved state, when set
+	Baseline *state.State
+
+	Reporter Reporter
+	Plan     *Plan
+}
+
+// changes finds the checked paths which changed since the previous state, or since
+// the requested time
+func (s Scope) changes(prev, check state.Map) state.Map {
+	if !s.Since.IsZero() {
+		return check.Since(s.Since)
+	}
+	return prev.Diff(check)
 }
@@ -0,0 +1,110 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+)
+
+// HasTag checks if a trigger has been given the tag
+func (t Trigger) HasTag(tag string) bool {
+	for _, tt := range t.Tags {
+		if tt == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Match finds the names of the triggers matching an expression, which is either
+// a name, a glob of names or a tag prefixed with "@". Manual triggers only match
+// their own name.
+func (tm Map) Match(expr string) (names []string) {
+	tag, isTag := strings.CutPrefix(expr, "@")
+	for name, t := range tm {
+		if t.runPolicy() == Manual && expr != name {
+			continue
+		}
+		if isTag {
+			if t.HasTag(tag) {
+				names = append(names, name)
+			}
+			continue
+		}
+		if ok, _ := path.Match(expr, name); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return
+}
+
+// Select finds the names of the triggers matching any of the expressions or tags, except
+// those matching any of the exclusions. When there are no expressions or tags, every
+// trigger is selected.
+func (tm Map) Select(exprs, tags, exclude []string) (names []string) {
+	for _, tag := range tags {
+		exprs = append(exprs, "@"+tag)
+	}
+	selected := make(map[string]bool)
+	if len(exprs) == 0 {
+		for name, t := range tm {
+			if t.runPolicy() != Manual {
+				selected[name] = true
+			}
+		}
+	}
+	for _, expr := range exprs {
+		matches := tm.Match(expr)
+		if len(matches) == 0 {
+			slog.Warn("Could not find trigger", "name", expr)
+		}
+		for _, name := range matches {
+			selected[name] = true
+		}
+	}
+	for _, expr := range exclude {
+		for _, name := range tm.Match(expr) {
+			delete(selected, name)
+		}
+	}
+	for name := range selected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// Tagged gets the triggers with any of the tags, warning about tags which no trigger
+// has like Select does. Unlike Select, manual triggers are included.
+func (tm Map) Tagged(tags []string) Map {
+	tagged := make(Map)
+	for _, tag := range tags {
+		found := false
+		for name, t := range tm {
+			if t.HasTag(tag) {
+				tagged[name] = t
+				found = true
+			}
+		}
+		if !found {
+			slog.Warn("Could not find trigger", "name", "@"+tag)
+		}
+	}
+	return tagged
+}
@@ -0,0 +1,111 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package triggers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMapSelect(t *testing.T) {
+	tm := Map{
+		"fonts":          {Name: "fonts", Tags: []string{"desktop", "cache"}},
+		"icon-caches":    {Name: "icon-caches", Tags: []string{"desktop", "cache"}},
+		"ldconfig":       {Name: "ldconfig", Tags: []string{"boot"}},
+		"systemd-reload": {Name: "systemd-reload", Tags: []string{"boot"}},
+		"systemd-reexec": {Name: "systemd-reexec", Tags: []string{"boot"}},
+		"vbox-restart":   {Name: "vbox-restart", Tags: []string{"boot"}, RunPolicy: Manual},
+	}
+	tests := []struct {
+		name    string
+		exprs   []string
+		tags    []string
+		exclude []string
+		want    []string
+	}{
+		{
+			name: "everything but manual triggers",
+			want: []string{"fonts", "icon-caches", "ldconfig", "systemd-reexec", "systemd-reload"},
+		},
+		{
+			name:  "names",
+			exprs: []string{"fonts", "ldconfig"},
+			want:  []string{"fonts", "ldconfig"},
+		},
+		{
+			name:  "glob",
+			exprs: []string{"systemd-*"},
+			want:  []string{"systemd-reexec", "systemd-reload"},
+		},
+		{
+			name:  "tag expression",
+			exprs: []string{"@cache"},
+			want:  []string{"fonts", "icon-caches"},
+		},
+		{
+			name: "tag flag leaves out manual triggers",
+			tags: []string{"boot"},
+			want: []string{"ldconfig", "systemd-reexec", "systemd-reload"},
+		},
+		{
+			name:  "manual trigger by name",
+			exprs: []string{"vbox-restart"},
+			want:  []string{"vbox-restart"},
+		},
+		{
+			name:    "exclusions",
+			exprs:   []string{"@boot", "@cache"},
+			exclude: []string{"systemd-*", "fonts"},
+			want:    []string{"icon-caches", "ldconfig"},
+		},
+		{
+			name:  "unknown",
+			exprs: []string{"missing"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tm.Select(tt.exprs, tt.tags, tt.exclude)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Select(%v, %v, %v) = %v, want %v", tt.exprs, tt.tags, tt.exclude, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapMatch(t *testing.T) {
+	tm := Map{
+		"gtk2-immodules": {Name: "gtk2-immodules", Tags: []string{"desktop"}},
+		"gtk3-immodules": {Name: "gtk3-immodules", Tags: []string{"desktop"}},
+		"qol-assist":     {Name: "qol-assist", Tags: []string{"desktop"}, RunPolicy: Manual},
+	}
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"gtk?-immodules", []string{"gtk2-immodules", "gtk3-immodules"}},
+		{"@desktop", []string{"gtk2-immodules", "gtk3-immodules"}},
+		{"*", []string{"gtk2-immodules", "gtk3-immodules"}},
+		{"qol-assist", []string{"qol-assist"}},
+		{"qol-*", nil},
+		{"[", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			if got := tm.Match(tt.expr); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Match(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
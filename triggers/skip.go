@@ -16,50 +16,100 @@ package triggers
 
 import (
 	"fmt"
+	"time"
+
 	"github.com/getsolus/usysconf/state"
 )
 
 // Skip contains details for when the configuration will not be executed, due to existing paths, or possible flags passed.
 // This supports globbing.
 type Skip struct {
-	Chroot bool     `toml:"chroot,omitempty"`
-	Live   bool     `toml:"live,omitempty"`
-	Paths  []string `toml:"paths"`
+	Chroot    bool     `toml:"chroot,omitempty"`
+	Live      bool     `toml:"live,omitempty"`
+	Container bool     `toml:"container,omitempty"`
+	Virt      bool     `toml:"virt,omitempty"`
+	Paths     []string `toml:"paths"`
+}
+
+// Matches checks if the scope is in one of the environments to skip, explaining why
+func (sk *Skip) Matches(s Scope) (bool, string) {
+	switch {
+	case sk == nil:
+		return false, ""
+	case sk.Chroot && s.Chroot:
+		return true, "running in a chroot"
+	case sk.Live && s.Live:
+		return true, "running from a live medium"
+	case sk.Container && s.Container != "":
+		return true, fmt.Sprintf("running in a container (%s)", s.Container)
+	case sk.Virt && s.Virt != "":
+		return true, fmt.Sprintf("running in a virtual machine (%s)", s.Virt)
+	}
+	return false, ""
 }
 
 // ShouldSkip will process the skip and check elements of the configuration and see if it should not be executed.
-func (t *Trigger) ShouldSkip(s Scope, check, diff state.Map) bool {
-	out := Output{
-		Status: Skipped,
+func (t *Trigger) ShouldSkip(s Scope, prev *state.State, e *Evaluation) bool {
+	t.Decide(s, prev, e)
+	s.planTrigger(t.Name, !e.Skip, e.Reason)
+	if e.Skip {
+		t.Output = append(t.Output, Output{Status: Skipped, Message: e.Reason})
 	}
+	return e.Skip
+}
+
+// skipReason decides if the trigger should be skipped, explaining why it will or won't run.
+// A changed definition counts as a change, like changed paths. The condition is only
+// evaluated when its outcome isn't in the Evaluation yet.
+func (t *Trigger) skipReason(s Scope, prev *state.State, e *Evaluation) (bool, string) {
+	policy := t.runPolicy()
+	check, diff, redefined := e.Check, e.Diff, e.Redefined
 	// Check if the paths exist, if not skip
-	if check.IsEmpty() || diff.IsEmpty() {
-		t.Output = append(t.Output, out)
-		return true
+	if check.IsEmpty() && (t.Check != nil || policy == OnChange) {
+		return true, "no check paths exist"
+	}
+	if policy == OnChange && diff.IsEmpty() && !redefined {
+		return true, "no changes since the last run"
+	}
+	at, ranOnce := prev.Once[t.Name]
+	if ranOnce && policy == Once && !s.Forced {
+		return true, fmt.Sprintf("already ran once, at %s", at.Local().Format(time.DateTime))
+	}
+	// Conditions are requirements, so they apply even when forced
+	if t.Condition != nil {
+		if e.Condition == nil {
+			e.Condition = t.Condition.Outcome(s, t.Owner)
+		}
+		if !e.Condition.Verdict.Passed() {
+			return true, fmt.Sprintf("condition not met: %s", e.Condition.Reason)
+		}
+	}
+	changed := fmt.Sprintf("%d changed paths", len(diff))
+	switch {
+	case ranOnce && policy == Once:
+		changed = fmt.Sprintf("already ran once, at %s", at.Local().Format(time.DateTime))
+	case policy != OnChange:
+		changed = policies[policy]
+	case redefined && diff.IsEmpty():
+		changed = "definition changed"
+	case redefined:
+		changed = "definition changed, " + changed
 	}
 	// Even if the skip element exists, if the force flag is present, continue processing
 	if s.Forced {
-		return false
+		return false, changed + ", forced"
 	}
 	if t.Skip == nil {
-		return false
-	}
-	// If the skip element exists and the chroot flag is present, skip
-	if t.Skip.Chroot && s.Chroot {
-		t.Output = append(t.Output, out)
-		return true
+		return false, changed
 	}
-	// If the skip element exists and the live flag is present, skip
-	if t.Skip.Live && s.Live {
-		t.Output = append(t.Output, out)
-		return true
+	// If the skip element exists and matches the environment, skip
+	if ok, reason := t.Skip.Matches(s); ok {
+		return true, reason
 	}
 	// Process through the skip paths, and if one is present within the system, skip
 	matches := check.Search(t.Skip.Paths)
 	for k := range matches {
-		out.Message = fmt.Sprintf("path '%s' found", k)
-		t.Output = append(t.Output, out)
-		return true
+		return true, fmt.Sprintf("path '%s' found", k)
 	}
-	return false
+	return false, changed
 }
@@ -0,0 +1,135 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package triggers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getsolus/usysconf/state"
+)
+
+func TestSkipReason(t *testing.T) {
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	check := state.Map{"/usr/share/fonts": at, "/usr/share/fonts/TTF": at}
+	ran := state.New()
+	ran.Once["once"] = at
+	tests := []struct {
+		name    string
+		trigger Trigger
+		scope   Scope
+		prev    *state.State
+		e       Evaluation
+		skip    bool
+		reason  string
+	}{
+		{
+			name:    "no check paths",
+			trigger: Trigger{Check: &Check{Paths: []string{"/usr/share/fonts"}}},
+			skip:    true,
+			reason:  "no check paths exist",
+		},
+		{
+			name:    "no changes",
+			trigger: Trigger{Check: &Check{}},
+			e:       Evaluation{Check: check},
+			skip:    true,
+			reason:  "no changes since the last run",
+		},
+		{
+			name:    "changed paths",
+			trigger: Trigger{Check: &Check{}},
+			e:       Evaluation{Check: check, Diff: state.Map{"/usr/share/fonts/TTF": at}},
+			reason:  "1 changed paths",
+		},
+		{
+			name:    "definition changed",
+			trigger: Trigger{Check: &Check{}},
+			e:       Evaluation{Check: check, Redefined: true},
+			reason:  "definition changed",
+		},
+		{
+			name:    "always without a check",
+			trigger: Trigger{RunPolicy: Always},
+			reason:  policies[Always],
+		},
+		{
+			name:    "already ran once",
+			trigger: Trigger{Name: "once", RunPolicy: Once},
+			prev:    ran,
+			skip:    true,
+			reason:  "already ran once",
+		},
+		{
+			name:    "forced to run once again",
+			trigger: Trigger{Name: "once", RunPolicy: Once},
+			scope:   Scope{Forced: true},
+			prev:    ran,
+			reason:  "forced",
+		},
+		{
+			name:    "condition not met, even when forced",
+			trigger: Trigger{RunPolicy: Always, Condition: &Condition{}},
+			scope:   Scope{Forced: true},
+			e:       Evaluation{Condition: &Outcome{Reason: "systemd is not PID1"}},
+			skip:    true,
+			reason:  "condition not met: systemd is not PID1",
+		},
+		{
+			name:    "condition not evaluated is assumed to pass",
+			trigger: Trigger{RunPolicy: Always, Condition: &Condition{}},
+			e:       Evaluation{Condition: &Outcome{Verdict: NotEvaluated}},
+			reason:  "runs every time",
+		},
+		{
+			name:    "condition evaluated when not provided",
+			trigger: Trigger{RunPolicy: Always, Condition: &Condition{Env: []string{"USYSCONF_TEST_UNSET"}}},
+			skip:    true,
+			reason:  "environment variable USYSCONF_TEST_UNSET is not set",
+		},
+		{
+			name:    "skipped in a chroot",
+			trigger: Trigger{RunPolicy: Always, Skip: &Skip{Chroot: true}},
+			scope:   Scope{Chroot: true},
+			skip:    true,
+			reason:  "running in a chroot",
+		},
+		{
+			name:    "forced in a chroot",
+			trigger: Trigger{RunPolicy: Always, Skip: &Skip{Chroot: true}},
+			scope:   Scope{Chroot: true, Forced: true},
+			reason:  "forced",
+		},
+		{
+			name:    "skip path found",
+			trigger: Trigger{Check: &Check{}, Skip: &Skip{Paths: []string{"/usr/share/fonts/TTF"}}},
+			e:       Evaluation{Check: check, Diff: check},
+			skip:    true,
+			reason:  "path '/usr/share/fonts/TTF' found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prev := tt.prev
+			if prev == nil {
+				prev = state.New()
+			}
+			skip, reason := tt.trigger.skipReason(tt.scope, prev, &tt.e)
+			if skip != tt.skip || !strings.Contains(reason, tt.reason) {
+				t.Errorf("skipReason() = %v, %q, want %v, %q", skip, reason, tt.skip, tt.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/getsolus/usysconf/state"
+)
+
+// staged is a path which was moved aside by a backup removal
+type staged struct {
+	orig string
+	path string
+	dir  bool
+	mode os.FileMode
+}
+
+// stagingRoot gets the directory used to hold backups during a run
+func (s Scope) stagingRoot() string {
+	id := s.ID
+	if id == "" {
+		id = "run"
+	}
+	return filepath.Join(filepath.Dir(state.Path), "staging", id)
+}
+
+// removeStaging removes the directory used to hold backups during a run, and the directory
+// holding it, unless they still contain anything which could not be restored
+func (s Scope) removeStaging() {
+	root := s.stagingRoot()
+	if err := os.Remove(root); err != nil && !os.IsNotExist(err) {
+		return
+	}
+	_ = os.Remove(filepath.Dir(root))
+}
+
+// StagingDir gets the directory used to hold backups for a trigger during a run
+func (t *Trigger) StagingDir(s Scope) string {
+	return filepath.Join(s.stagingRoot(), t.Name)
+}
+
+// stage moves a path into the staging directory. Directories are only recorded and
+// removed, since their contents will already have been moved.
+func (t *Trigger) stage(s Scope, path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		if err = os.Remove(path); err != nil {
+			return err
+		}
+		t.staged = append(t.staged, staged{orig: path, dir: true, mode: info.Mode().Perm()})
+		return nil
+	}
+	dest := filepath.Join(t.StagingDir(s), path)
+	if err = os.MkdirAll(filepath.Dir(dest), 0700); err != nil {
+		return err
+	}
+	if err = move(path, dest); err != nil {
+		return err
+	}
+	t.staged = append(t.staged, staged{orig: path, path: dest})
+	return nil
+}
+
+// Unstage restores backed up paths if requested, then discards the staging directory
+func (t *Trigger) Unstage(s Scope, restore bool) {
+	if len(t.staged) == 0 {
+		return
+	}
+	if restore {
+		slog.Info("Restoring backed up paths", "trigger", t.Name, "count", len(t.staged))
+		for i := len(t.staged) - 1; i >= 0; i-- {
+			entry := t.staged[i]
+			var err error
+			if entry.dir {
+				if err = os.Mkdir(entry.orig, entry.mode); os.IsExist(err) {
+					err = nil
+				}
+			} else {
+				err = move(entry.path, entry.orig)
+			}
+			if err != nil {
+				out := Output{
+					Status:  Failure,
+					Message: fmt.Sprintf("Failed to restore path '%s' from '%s', reason: %s\n", entry.orig, entry.path, err),
+				}
+				t.Output = append(t.Output, out)
+				// Keep the staging directory so nothing is lost
+				return
+			}
+		}
+	}
+	t.staged = nil
+	if err := os.RemoveAll(t.StagingDir(s)); err != nil {
+		slog.Warn("Failed to purge staging directory", "path", t.StagingDir(s), "reason", err)
+	}
+}
+
+// move renames a file, falling back to copying it when crossing filesystems
+func move(from, to string) error {
+	err := os.Rename(from, to)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	if err = copyFile(from, to); err != nil {
+		_ = os.Remove(to)
+		return err
+	}
+	return os.Remove(from)
+}
+
+// copyFile duplicates a regular file or symlink, keeping its mode and ownership
+func copyFile(from, to string) error {
+	info, err := os.Lstat(from)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(from)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, to)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("cannot copy special file '%s'", from)
+	}
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return os.Lchown(to, int(stat.Uid), int(stat.Gid))
+	}
+	return nil
+}
@@ -25,3 +25,17 @@ const (
 	// Failure - The configuration was not be executed, due to error.
 	Failure
 )
+
+// String gets a human-readable name for the Status
+func (s Status) String() string {
+	switch s {
+	case Skipped:
+		return "skipped"
+	case Success:
+		return "success"
+	case Failure:
+		return "failure"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+)
+
+// TailSize is the maximum number of bytes of output kept for failure reports
+const TailSize = 16 * 1024
+
+// StreamOutput is where live output from binaries is sent when streaming
+var StreamOutput io.Writer = os.Stdout
+
+// streamLock prevents lines from different binaries being interleaved
+var streamLock sync.Mutex
+
+// tail keeps only the most recent bytes written to it
+type tail struct {
+	buff []byte
+	max  int
+}
+
+// Write appends to the buffer, discarding the oldest bytes when full
+func (t *tail) Write(p []byte) (int, error) {
+	t.buff = append(t.buff, p...)
+	if over := len(t.buff) - t.max; over > 0 {
+		t.buff = t.buff[over:]
+	}
+	return len(p), nil
+}
+
+// String gets the contents of the buffer
+func (t *tail) String() string {
+	return string(t.buff)
+}
+
+// prefixWriter forwards complete lines to StreamOutput with a label in front of them
+type prefixWriter struct {
+	prefix  []byte
+	partial []byte
+}
+
+// newPrefixWriter creates a writer which prefixes lines with "[label] "
+func newPrefixWriter(label string) *prefixWriter {
+	return &prefixWriter{prefix: []byte("[" + label + "] ")}
+}
+
+// Write sends every complete line, holding back anything after the last newline
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.partial = append(p.partial, b...)
+	for {
+		i := bytes.IndexByte(p.partial, '\n')
+		if i < 0 {
+			break
+		}
+		p.emit(p.partial[:i+1])
+		p.partial = p.partial[i+1:]
+	}
+	return len(b), nil
+}
+
+// Flush sends any remaining incomplete line
+func (p *prefixWriter) Flush() {
+	if len(p.partial) == 0 {
+		return
+	}
+	p.emit(append(p.partial, '\n'))
+	p.partial = nil
+}
+
+// emit writes a single line while holding the stream lock
+func (p *prefixWriter) emit(line []byte) {
+	streamLock.Lock()
+	defer streamLock.Unlock()
+	_, _ = StreamOutput.Write(append(append([]byte{}, p.prefix...), line...))
+}
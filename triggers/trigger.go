@@ -15,88 +15,171 @@
 package triggers
 
 import (
+	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/getsolus/usysconf/state"
 )
 
 // Trigger contains all the information for a configuration to be executed and output to the user.
 type Trigger struct {
-	Name   string
-	Path   string
-	Output []Output
+	Name   string   `toml:"-"`
+	Path   string   `toml:"-"`
+	Owner  string   `toml:"-"`
+	Output []Output `toml:"-"`
+
+	staged []staged
+	abort  bool
 
 	Description string            `toml:"description"`
+	Tags        []string          `toml:"tags,omitempty"`
+	RunPolicy   string            `toml:"run_policy,omitempty"`
+	OnFailure   string            `toml:"on_failure,omitempty"`
 	Check       *Check            `toml:"check,omitempty"`
 	Skip        *Skip             `toml:"skip,omitempty"`
+	Condition   *Condition        `toml:"condition,omitempty"`
 	Deps        *Deps             `toml:"deps,omitempty"`
 	Env         map[string]string `toml:"env,omitempty"`
 	Bins        []Bin             `toml:"bins,omitempty"`
 	Removals    []Remove          `toml:"remove,omitempty"`
+	Dirs        []Dir             `toml:"dirs,omitempty"`
+	Symlinks    []Symlink         `toml:"symlinks,omitempty"`
+	Files       []File            `toml:"files,omitempty"`
+}
+
+// Result summarises the outcome of running a single trigger.
+type Result struct {
+	Name     string
+	Status   Status
+	Duration time.Duration
+	Changed  int
+	Message  string
+	Abort    bool
 }
 
 // Run will process a single configuration and scope.
-func (t *Trigger) Run(s Scope, prev, next state.Map) (ok bool) {
+func (t *Trigger) Run(s Scope, prev, next *state.State) (r Result) {
 	var check, diff state.Map
+	var hash string
+	var ok bool
+	var err error
+	start := time.Now()
+	r.Name = t.Name
 	// Get the new check result
 	if check, ok = t.CheckMatch(); !ok {
+		s.planTrigger(t.Name, false, "failed to scan check paths")
+		t.abort = t.OnFailure == StopRun
 		goto FINISH
 	}
 	// Calculate Diff
-	diff = prev.Diff(check)
-	// Merge it into the new State
-	next.Merge(diff)
+	diff = s.changes(prev.Paths, check)
+	r.Changed = len(diff)
+	if hash, err = t.Hash(); err != nil {
+		t.Output = append(t.Output, Output{
+			Status:  Failure,
+			Message: fmt.Sprintf("Failed to hash the definition of '%s', reason: %s", t.Name, err),
+		})
+		s.planTrigger(t.Name, false, "failed to hash the definition")
+		t.abort = t.OnFailure == StopRun
+		goto FINISH
+	}
+	// Merge the latest check and definition into the new State
+	next.Paths.Merge(check)
+	// Forget the checked paths which no longer exist
+	if t.Check != nil {
+		next.Paths.Delete(next.Paths.Within(t.Check.Paths).Missing(check))
+	}
+	next.Definitions[t.Name] = hash
 	// Check for Skip
-	if t.ShouldSkip(s, check, diff) {
+	if t.ShouldSkip(s, prev, &Evaluation{
+		Check: check, Diff: diff, Hash: hash, Redefined: prev.Redefined(t.Name, hash),
+	}) {
 		goto FINISH
 	}
 	// Do the removals
-	if ok = t.Remove(s); !ok {
+	if !t.Remove(s) {
+		t.abort = t.OnFailure == StopRun
+		goto FINISH
+	}
+	// Create the files
+	if !t.CreateFiles(s) {
+		t.abort = t.OnFailure == StopRun
 		goto FINISH
 	}
 	// Run the bins
 	t.ExecuteBins(s)
 FINISH:
+	// Put back anything that was backed up if the trigger failed
+	t.Unstage(s, t.Status() == Failure)
 	t.Finish(s)
+	r.Status = t.Status()
+	r.Abort = t.abort
+	// Remember that a trigger meant to run once has done so
+	if r.Status == Success && t.runPolicy() == Once {
+		next.Once[t.Name] = time.Now()
+	}
+	r.Duration = time.Since(start)
+	for _, out := range t.Output {
+		if out.Status == r.Status && len(out.Message) > 0 {
+			r.Message = out.Message
+			break
+		}
+	}
 	return
 }
 
-// Finish is the last function to be executed by any trigger to output details to the user.
-func (t *Trigger) Finish(s Scope) {
-	// Check for the worst status
+// Status finds the worst status of all the outputs for this trigger.
+func (t *Trigger) Status() Status {
 	status := Skipped
 	for _, out := range t.Output {
 		if out.Status > status {
 			status = out.Status
 		}
 	}
+	return status
+}
+
+// Finish is the last function to be executed by any trigger to output details to the user.
+func (t *Trigger) Finish(s Scope) {
+	// Check for the worst status
+	status := t.Status()
+	logger := slog.With("trigger", t.Name)
 	// Indicate the worst status for the whole group
 	switch status {
 	case Skipped:
-		slog.Debug(t.Name)
+		logger.Debug(t.Name, "status", status)
 	case Failure:
-		slog.Error(t.Name)
+		logger.Error(t.Name, "status", status)
 	case Success:
-		slog.Info(t.Name)
+		logger.Info(t.Name, "status", status)
 	}
 	// Indicate status for sub-tasks
 	for _, out := range t.Output {
+		if len(out.Attempts) > 1 {
+			for i, attempt := range out.Attempts {
+				logger.Info("Attempt", "task", out.Name, "subtask", out.SubTask, "attempt", i+1, "status", attempt.Status,
+					"code", attempt.Code, "duration", attempt.Duration)
+			}
+		}
 		switch out.Status {
 		case Skipped:
 			if len(out.SubTask) > 0 {
-				slog.Debug("Skipped", "subtask", out.SubTask, "reason", out.Message)
+				logger.Debug("Skipped", "subtask", out.SubTask, "status", out.Status, "reason", out.Message)
 			} else if len(out.Message) > 0 {
-				slog.Debug("Skipped", "reason", out.Message)
+				logger.Debug("Skipped", "task", out.Name, "status", out.Status, "reason", out.Message)
 			}
 		case Failure:
 			if len(out.SubTask) > 0 {
-				slog.Error("Failed", "subtask", out.SubTask, "reason", out.Message)
+				logger.Error("Failed", "subtask", out.SubTask, "status", out.Status, "reason", out.Message, "output", out.Captured)
 			} else if len(out.Message) > 0 {
-				slog.Error("Failed", "reason", out.Message)
+				logger.Error("Failed", "task", out.Name, "status", out.Status, "reason", out.Message, "output", out.Captured)
 			}
 		case Success:
 			if s.DryRun && len(out.SubTask) > 0 {
-				slog.Info(out.SubTask)
+				logger.Info(out.SubTask, "subtask", out.SubTask, "status", out.Status)
+			} else if len(out.Attempts) > 1 {
+				logger.Info("Retried", "task", out.Name, "subtask", out.SubTask, "status", out.Status, "reason", out.Message)
 			}
 		}
 	}
@@ -0,0 +1,111 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ValidateOwner checks that a trigger loaded from a user's directory only does
+// things which are safe to run as that user. Nothing may be done as root on behalf
+// of the owner, so bins can't raise their priority, create cgroups or keep capabilities.
+func (t *Trigger) ValidateOwner() error {
+	if len(t.Removals) > 0 || len(t.Dirs) > 0 || len(t.Symlinks) > 0 || len(t.Files) > 0 {
+		return fmt.Errorf("user triggers may only contain [[bins]]")
+	}
+	for _, b := range t.Bins {
+		if b.User != "" && b.User != t.Owner {
+			return fmt.Errorf("bin '%s' cannot run as '%s' from a trigger owned by '%s'", b.Task, b.User, t.Owner)
+		}
+		if b.Group != "" {
+			return fmt.Errorf("bin '%s' cannot change group from a trigger owned by '%s'", b.Task, t.Owner)
+		}
+		if (b.Nice != nil && *b.Nice < 0) || (b.IONice != nil && b.IONice.Class == "realtime") {
+			return fmt.Errorf("bin '%s' cannot raise its priority from a trigger owned by '%s'", b.Task, t.Owner)
+		}
+		if b.Cgroup != nil {
+			return fmt.Errorf("bin '%s' cannot set cgroup limits from a trigger owned by '%s'", b.Task, t.Owner)
+		}
+		if b.Sandbox != nil && len(b.Sandbox.Capabilities) > 0 {
+			return fmt.Errorf("bin '%s' cannot keep capabilities from a trigger owned by '%s'", b.Task, t.Owner)
+		}
+	}
+	return nil
+}
+
+// credential works out who a bin should run as, returning nil to keep the current user
+func (b *Bin) credential(owner string) (*syscall.Credential, *user.User, error) {
+	name := b.User
+	if owner != "" {
+		name = owner
+	}
+	if name == "" && b.Group == "" {
+		return nil, nil, nil
+	}
+	u, err := user.Current()
+	if name != "" {
+		u, err = user.Lookup(name)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	uid, _ := strconv.ParseUint(u.Uid, 10, 32)
+	gid, _ := strconv.ParseUint(u.Gid, 10, 32)
+	cred := &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}
+	if b.Group != "" {
+		g, err := user.LookupGroup(b.Group)
+		if err != nil {
+			return nil, nil, err
+		}
+		gid, _ = strconv.ParseUint(g.Gid, 10, 32)
+		cred.Gid = uint32(gid)
+	}
+	if name != "" {
+		groups, err := u.GroupIds()
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, group := range groups {
+			id, err := strconv.ParseUint(group, 10, 32)
+			if err == nil {
+				cred.Groups = append(cred.Groups, uint32(id))
+			}
+		}
+	} else {
+		cred.NoSetGroups = true
+	}
+	return cred, u, nil
+}
+
+// userEnv builds a minimal environment for the user, keeping only the locale, terminal
+// and search path of the current one, so nothing of root's environment leaks to the user
+func userEnv(u *user.User) (env []string) {
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		switch {
+		case name == "PATH", name == "LANG", name == "LANGUAGE", name == "TERM", name == "TZ":
+		case strings.HasPrefix(name, "LC_"):
+		default:
+			continue
+		}
+		env = append(env, kv)
+	}
+	return append(env, "HOME="+u.HomeDir, "USER="+u.Username, "LOGNAME="+u.Username)
+}
@@ -0,0 +1,62 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/getsolus/usysconf/triggers"
+)
+
+// Plain prints one line per finished trigger, for when the output is not a terminal
+type Plain struct {
+	out  io.Writer
+	lock sync.Mutex
+}
+
+// NewPlain creates a Plain renderer which writes to out
+func NewPlain(out io.Writer) *Plain {
+	return &Plain{out: out}
+}
+
+// Start does nothing, since only finished triggers are printed
+func (p *Plain) Start(string) {}
+
+// Progress does nothing, since only finished triggers are printed
+func (p *Plain) Progress(string, int, int) {}
+
+// Finish prints the outcome of a trigger
+func (p *Plain) Finish(r triggers.Result) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	fmt.Fprintf(p.out, "%-8s %s (%s)\n", r.Status, r.Name, r.Duration.Round(time.Millisecond))
+}
+
+// Write passes log output straight through
+func (p *Plain) Write(b []byte) (int, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.out.Write(b)
+}
+
+// Close prints the summary table
+func (p *Plain) Close(results []triggers.Result) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	Summary(p.out, results)
+}
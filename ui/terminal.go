@@ -0,0 +1,181 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getsolus/usysconf/triggers"
+)
+
+// spinner is the sequence of frames shown for running triggers
+var spinner = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// symbols marks each finished trigger with its status
+var symbols = map[triggers.Status]string{
+	triggers.Skipped: "\x1b[2m-\x1b[0m",
+	triggers.Success: "\x1b[32m✓\x1b[0m",
+	triggers.Failure: "\x1b[31m✗\x1b[0m",
+}
+
+// maxRows limits the list to the most recent triggers so it fits on screen
+const maxRows = 20
+
+// row is the state of a single trigger in the list
+type row struct {
+	name     string
+	start    time.Time
+	duration time.Duration
+	done     int
+	total    int
+	finished bool
+	status   triggers.Status
+}
+
+// Terminal redraws a live list of triggers in place
+type Terminal struct {
+	out   io.Writer
+	lock  sync.Mutex
+	rows  []*row
+	index map[string]*row
+	lines int
+	frame int
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// NewTerminal creates a Terminal renderer and starts redrawing it
+func NewTerminal(out io.Writer) *Terminal {
+	t := &Terminal{
+		out:   out,
+		index: make(map[string]*row),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go t.animate()
+	return t
+}
+
+// animate advances the spinner until the renderer is closed
+func (t *Terminal) animate() {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	defer close(t.done)
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+			t.lock.Lock()
+			t.frame = (t.frame + 1) % len(spinner)
+			t.redraw()
+			t.lock.Unlock()
+		}
+	}
+}
+
+// Start adds a trigger to the list
+func (t *Terminal) Start(name string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	r := &row{name: name, start: time.Now()}
+	t.rows = append(t.rows, r)
+	t.index[name] = r
+	t.redraw()
+}
+
+// Progress updates the sub-task count for a trigger
+func (t *Terminal) Progress(name string, done, total int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if r, ok := t.index[name]; ok {
+		r.done, r.total = done, total
+	}
+	t.redraw()
+}
+
+// Finish marks a trigger as done
+func (t *Terminal) Finish(res triggers.Result) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if r, ok := t.index[res.Name]; ok {
+		r.finished = true
+		r.status = res.Status
+		r.duration = res.Duration
+	}
+	t.redraw()
+}
+
+// Write prints log output above the list
+func (t *Terminal) Write(b []byte) (int, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.clear()
+	n, err := t.out.Write(b)
+	t.draw()
+	return n, err
+}
+
+// Close stops redrawing and prints the summary table
+func (t *Terminal) Close(results []triggers.Result) {
+	close(t.stop)
+	<-t.done
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.redraw()
+	Summary(t.out, results)
+}
+
+// clear removes the previously drawn list
+func (t *Terminal) clear() {
+	if t.lines > 0 {
+		fmt.Fprintf(t.out, "\x1b[%dA\x1b[J", t.lines)
+	}
+	t.lines = 0
+}
+
+// draw prints the list of triggers
+func (t *Terminal) draw() {
+	var b strings.Builder
+	rows := t.rows
+	if len(rows) > maxRows {
+		rows = rows[len(rows)-maxRows:]
+	}
+	for _, r := range rows {
+		symbol := spinner[t.frame]
+		elapsed := time.Since(r.start)
+		if r.finished {
+			symbol = symbols[r.status]
+			elapsed = r.duration
+		}
+		fmt.Fprintf(&b, " %s %s", symbol, r.name)
+		if r.total > 1 {
+			fmt.Fprintf(&b, " %d/%d", r.done, r.total)
+		}
+		fmt.Fprintf(&b, " \x1b[2m(%s)\x1b[0m\n", elapsed.Round(100*time.Millisecond))
+	}
+	t.lines = len(rows)
+	_, _ = io.WriteString(t.out, b.String())
+}
+
+// redraw replaces the list with its current state
+func (t *Terminal) redraw() {
+	t.clear()
+	t.draw()
+}
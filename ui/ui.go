@@ -0,0 +1,77 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/getsolus/usysconf/triggers"
+)
+
+// Renderer displays the progress of a run and summarises it at the end
+type Renderer interface {
+	triggers.Reporter
+	io.Writer
+	// Close stops any live updates and prints the summary table
+	Close(results []triggers.Result)
+}
+
+// New creates a live Renderer for terminals, or a plain one for anything else
+func New(f *os.File) Renderer {
+	if IsTerminal(f) {
+		return NewTerminal(f)
+	}
+	return NewPlain(f)
+}
+
+// IsTerminal checks if the file is connected to a terminal
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Summary prints a table of the number of triggers and time spent for each status
+func Summary(w io.Writer, results []triggers.Result) {
+	statuses := []triggers.Status{triggers.Success, triggers.Skipped, triggers.Failure}
+	counts := make(map[triggers.Status]int)
+	durations := make(map[triggers.Status]time.Duration)
+	var total time.Duration
+	for _, r := range results {
+		counts[r.Status]++
+		durations[r.Status] += r.Duration
+		total += r.Duration
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%-10s %6s %10s\n", "Status", "Count", "Time")
+	for _, status := range statuses {
+		fmt.Fprintf(w, "%-10s %6d %10s\n", status, counts[status], durations[status].Round(time.Millisecond))
+	}
+	fmt.Fprintf(w, "%-10s %6d %10s\n", "total", len(results), total.Round(time.Millisecond))
+	for _, r := range results {
+		if r.Status == triggers.Failure {
+			fmt.Fprintf(w, "\nFailed: %s", r.Name)
+			if r.Message != "" {
+				fmt.Fprintf(w, ": %s", r.Message)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+}
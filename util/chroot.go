@@ -15,58 +15,40 @@
 package util
 
 import (
-	"io"
+	"fmt"
 	"log/slog"
-	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
-	"syscall"
 )
 
 // IsChroot detects if the current process is running in a chroot environment
-func IsChroot() bool {
-	var raw []byte
-	var root, chroot *syscall.Stat_t
-	var rootDir, chrootDir os.FileInfo
-	var pid int
+func IsChroot() (bool, error) {
 	// Try to check for access to the root partition of PID1 (shell?)
-	if _, err := os.Stat("/proc/1/root"); err != nil {
-		slog.Warn("Failed to access, assuming chroot and continuing", "path", "/proc/1/root")
-		return true
+	if _, err := FS.Stat("/proc/1/root"); err != nil {
+		return false, fmt.Errorf("failed to access the root of PID1: %w", err)
 	}
 	// Check /proc/mounts for overlayfs on "/"
-	mounts, err := os.Open("/proc/mounts")
+	raw, err := FS.ReadFile("/proc/mounts")
 	if err != nil {
-		slog.Warn("Failed to access", "path", "/proc/mounts", "reason", err)
-		goto FALLBACK
+		slog.Debug("Failed to read", "path", "/proc/mounts", "reason", err)
+	} else if strings.Contains(string(raw), "overlay / overlay") {
+		// Containers commonly use an overlayfs root too
+		if !IsContainer() {
+			slog.Debug("Overlayfs for '/' found, assuming chroot")
+			return true, nil
+		}
+		slog.Debug("Overlayfs for '/' found inside container, not assuming chroot")
 	}
-	raw, err = io.ReadAll(mounts)
-	if err != nil {
-		slog.Warn("Failed to read", "path", "/proc/mounts", "reason", err)
-		goto FALLBACK
-	}
-	_ = mounts.Close()
-	if strings.Contains(string(raw), "overlay / overlay") {
-		slog.Debug("Overlayfs for '/' found, assuming chroot")
-		return true
-	}
-FALLBACK:
 	slog.Debug("Falling back to rigorous check for chroot")
-	rootDir, err = os.Stat("/")
+	rootDir, err := FS.Stat("/")
 	if err != nil {
-		slog.Error("Failed to access", "path", "/", "reason", err)
-		// TODO: Return error instead of panicking.
-		panic("Failed to access")
+		return false, fmt.Errorf("failed to access '/': %w", err)
 	}
-	pid = os.Getpid()
-	chrootDir, err = os.Stat(filepath.Join("/", "proc", strconv.Itoa(pid), "root"))
+	chrootPath := filepath.Join("/", "proc", strconv.Itoa(FS.Getpid()), "root")
+	chrootDir, err := FS.Stat(chrootPath)
 	if err != nil {
-		slog.Error("Failed to access", "path", "/", "reason", err)
-		// TODO: Return error instead of panicking.
-		panic("Failed to access")
+		return false, fmt.Errorf("failed to access '%s': %w", chrootPath, err)
 	}
-	root = rootDir.Sys().(*syscall.Stat_t)
-	chroot = chrootDir.Sys().(*syscall.Stat_t)
-	return root.Dev != chroot.Dev || root.Ino != chroot.Ino
+	return !FS.SameFile(rootDir, chrootDir), nil
 }
@@ -0,0 +1,95 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"io/fs"
+	"testing"
+)
+
+func TestIsChroot(t *testing.T) {
+	root := fakeFile{dir: true, inode: 2}
+	other := fakeFile{dir: true, inode: 3}
+	tests := []struct {
+		name    string
+		files   map[string]fakeFile
+		errs    map[string]error
+		want    bool
+		wantErr bool
+	}{
+		{
+			name:    "missing /proc",
+			files:   map[string]fakeFile{"/": root},
+			wantErr: true,
+		},
+		{
+			name: "same root as this process",
+			files: map[string]fakeFile{
+				"/": root, "/proc/1/root": root, "/proc/42/root": root,
+				"/proc/mounts": {data: "/dev/sda1 / ext4 rw 0 0\n"},
+			},
+		},
+		{
+			name: "different root from this process",
+			files: map[string]fakeFile{
+				"/": root, "/proc/1/root": root, "/proc/42/root": other,
+				"/proc/mounts": {data: "/dev/sda1 / ext4 rw 0 0\n"},
+			},
+			want: true,
+		},
+		{
+			name: "overlay root",
+			files: map[string]fakeFile{
+				"/": root, "/proc/1/root": root, "/proc/42/root": root,
+				"/proc/mounts": {data: "overlay / overlay rw 0 0\n"},
+			},
+			want: true,
+		},
+		{
+			name: "overlay root in a container",
+			files: map[string]fakeFile{
+				"/": root, "/proc/1/root": root, "/proc/42/root": root,
+				"/proc/mounts": {data: "overlay / overlay rw 0 0\n"},
+				"/.dockerenv":  {},
+			},
+		},
+		{
+			name: "unreadable mounts",
+			files: map[string]fakeFile{
+				"/": root, "/proc/1/root": root, "/proc/42/root": other,
+			},
+			errs: map[string]error{"/proc/mounts": fs.ErrPermission},
+			want: true,
+		},
+		{
+			name:    "inaccessible root of this process",
+			files:   map[string]fakeFile{"/": root, "/proc/1/root": root},
+			errs:    map[string]error{"/proc/42/root": fs.ErrPermission},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFS(t, &fakeFS{pid: 42, files: tt.files, errs: tt.errs})
+			got, err := IsChroot()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("IsChroot() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("IsChroot() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Environment describes the kind of system this process is running on
+type Environment struct {
+	Chroot    bool
+	Live      bool
+	Container string
+	Virt      string
+	Reasons   []string
+
+	// ChrootErr and LiveErr are set when Chroot and Live could not be detected
+	ChrootErr error
+	LiveErr   error
+}
+
+// DetectEnvironment checks for a chroot, live medium, container and virtual machine
+func DetectEnvironment() (env Environment) {
+	env.Container, env.Reasons = detectContainer()
+	var reasons []string
+	env.Virt, reasons = detectVirt()
+	env.Reasons = append(env.Reasons, reasons...)
+	env.Chroot, env.ChrootErr = IsChroot()
+	switch {
+	case env.ChrootErr != nil:
+		env.Reasons = append(env.Reasons, fmt.Sprintf("chroot: unknown, %s", env.ChrootErr))
+	case env.Chroot:
+		env.Reasons = append(env.Reasons, "chroot: root differs from the root of PID1")
+	}
+	env.Live, env.LiveErr = IsLive()
+	switch {
+	case env.LiveErr != nil:
+		env.Reasons = append(env.Reasons, fmt.Sprintf("live: unknown, %s", env.LiveErr))
+	case env.Live:
+		env.Reasons = append(env.Reasons, "live: /run/initramfs/livedev exists")
+	}
+	return
+}
+
+// IsContainer checks if this process is running inside of a container
+func IsContainer() bool {
+	name, _ := detectContainer()
+	return name != ""
+}
+
+// IsSystemd checks if systemd is running as PID1
+func IsSystemd() bool {
+	info, err := FS.Stat("/run/systemd/system")
+	return err == nil && info.IsDir()
+}
+
+// detectContainer finds the type of container, if any, and how it was identified
+func detectContainer() (name string, reasons []string) {
+	found := func(n, reason string) (string, []string) {
+		return n, []string{fmt.Sprintf("container: %s from %s", n, reason)}
+	}
+	if raw, err := FS.ReadFile("/run/systemd/container"); err == nil {
+		if n := strings.TrimSpace(string(raw)); n != "" {
+			return found(n, "/run/systemd/container")
+		}
+	}
+	if environ, err := FS.ReadFile("/proc/1/environ"); err == nil {
+		for _, kv := range bytes.Split(environ, []byte{0}) {
+			if n, ok := bytes.CutPrefix(kv, []byte("container=")); ok && len(n) > 0 {
+				return found(string(n), "the environment of PID1")
+			}
+		}
+	}
+	if _, err := FS.Stat("/.dockerenv"); err == nil {
+		return found("docker", "/.dockerenv")
+	}
+	if _, err := FS.Stat("/run/.containerenv"); err == nil {
+		return found("podman", "/run/.containerenv")
+	}
+	if raw, err := FS.ReadFile("/proc/1/cgroup"); err == nil {
+		cgroups := string(raw)
+		for _, known := range []struct{ marker, name string }{
+			{"/docker", "docker"},
+			{"/libpod", "podman"},
+			{"/lxc", "lxc"},
+			{"kubepods", "kubernetes"},
+		} {
+			if strings.Contains(cgroups, known.marker) {
+				return found(known.name, "/proc/1/cgroup")
+			}
+		}
+	}
+	if raw, err := FS.ReadFile("/proc/sys/kernel/osrelease"); err == nil {
+		release := strings.ToLower(string(raw))
+		if strings.Contains(release, "microsoft") || strings.Contains(release, "wsl") {
+			return found("wsl", "/proc/sys/kernel/osrelease")
+		}
+	}
+	return "", nil
+}
+
+// dmiVendors maps identifying DMI strings to virtualisation types
+var dmiVendors = []struct{ marker, name string }{
+	{"KVM", "kvm"},
+	{"QEMU", "qemu"},
+	{"VMware", "vmware"},
+	{"VirtualBox", "oracle"},
+	{"innotek", "oracle"},
+	{"Xen", "xen"},
+	{"Bochs", "bochs"},
+	{"Parallels", "parallels"},
+	{"Amazon EC2", "amazon"},
+	{"Google Compute Engine", "google"},
+	{"Microsoft Corporation Virtual Machine", "microsoft"},
+}
+
+// detectVirt finds the type of virtual machine, if any, and how it was identified
+func detectVirt() (name string, reasons []string) {
+	var dmi []string
+	for _, field := range []string{"sys_vendor", "product_name", "bios_vendor", "board_vendor"} {
+		if raw, err := FS.ReadFile("/sys/class/dmi/id/" + field); err == nil {
+			dmi = append(dmi, strings.TrimSpace(string(raw)))
+		}
+	}
+	joined := strings.Join(dmi, " ")
+	for _, vendor := range dmiVendors {
+		if strings.Contains(joined, vendor.marker) {
+			return vendor.name, []string{fmt.Sprintf("virt: %s from DMI (%s)", vendor.name, joined)}
+		}
+	}
+	if _, err := FS.Stat("/proc/xen"); err == nil {
+		return "xen", []string{"virt: xen from /proc/xen"}
+	}
+	if raw, err := FS.ReadFile("/proc/cpuinfo"); err == nil {
+		for _, line := range strings.Split(string(raw), "\n") {
+			if strings.HasPrefix(line, "flags") && strings.Contains(line, " hypervisor") {
+				return "unknown", []string{"virt: unknown hypervisor from the CPU flags"}
+			}
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,42 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import "testing"
+
+func TestDetectEnvironment(t *testing.T) {
+	useFS(t, &fakeFS{pid: 42, files: map[string]fakeFile{
+		"/run/systemd/container":       {data: "systemd-nspawn\n"},
+		"/run/systemd/system":          {dir: true},
+		"/run/initramfs/livedev":       {data: "/dev/sr0"},
+		"/sys/class/dmi/id/sys_vendor": {data: "QEMU\n"},
+	}})
+	env := DetectEnvironment()
+	if env.Container != "systemd-nspawn" {
+		t.Errorf("Container = %q, want %q", env.Container, "systemd-nspawn")
+	}
+	if env.Virt != "qemu" {
+		t.Errorf("Virt = %q, want %q", env.Virt, "qemu")
+	}
+	if !env.Live || env.LiveErr != nil {
+		t.Errorf("Live = %v (%v), want true", env.Live, env.LiveErr)
+	}
+	if env.ChrootErr == nil {
+		t.Errorf("ChrootErr = nil, want an error without /proc/1/root")
+	}
+	if !IsSystemd() {
+		t.Errorf("IsSystemd() = false, want true")
+	}
+}
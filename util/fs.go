@@ -0,0 +1,55 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// FileSystem is the view of the system used to detect the environment
+type FileSystem interface {
+	Stat(name string) (fs.FileInfo, error)
+	ReadFile(name string) ([]byte, error)
+	SameFile(a, b fs.FileInfo) bool
+	Getpid() int
+}
+
+// FS is the file system inspected by the detection functions, replaceable to simulate other systems
+var FS FileSystem = osFS{}
+
+// osFS inspects the real file system
+type osFS struct{}
+
+// Stat returns the FileInfo of the named file
+func (osFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+// ReadFile reads the entire named file
+func (osFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+// SameFile reports whether both FileInfos describe the same file
+func (osFS) SameFile(a, b fs.FileInfo) bool {
+	return os.SameFile(a, b)
+}
+
+// Getpid returns the process id of this process
+func (osFS) Getpid() int {
+	return syscall.Getpid()
+}
@@ -0,0 +1,86 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"io/fs"
+	"testing"
+	"time"
+)
+
+// fakeFS is an in-memory FileSystem. Files with the same inode are the same file.
+type fakeFS struct {
+	pid   int
+	files map[string]fakeFile
+	errs  map[string]error
+}
+
+// fakeFile is a file or directory in a fakeFS
+type fakeFile struct {
+	data  string
+	dir   bool
+	inode int
+}
+
+// fakeInfo describes a fakeFile
+type fakeInfo struct {
+	name string
+	file fakeFile
+}
+
+func (i fakeInfo) Name() string       { return i.name }
+func (i fakeInfo) Size() int64        { return int64(len(i.file.data)) }
+func (i fakeInfo) Mode() fs.FileMode  { return 0o644 }
+func (i fakeInfo) ModTime() time.Time { return time.Time{} }
+func (i fakeInfo) IsDir() bool        { return i.file.dir }
+func (i fakeInfo) Sys() any           { return nil }
+
+func (f *fakeFS) Stat(name string) (fs.FileInfo, error) {
+	if err, ok := f.errs[name]; ok {
+		return nil, err
+	}
+	file, ok := f.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return fakeInfo{name: name, file: file}, nil
+}
+
+func (f *fakeFS) ReadFile(name string) ([]byte, error) {
+	if err, ok := f.errs[name]; ok {
+		return nil, err
+	}
+	file, ok := f.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return []byte(file.data), nil
+}
+
+func (f *fakeFS) SameFile(a, b fs.FileInfo) bool {
+	return a.(fakeInfo).file.inode == b.(fakeInfo).file.inode
+}
+
+func (f *fakeFS) Getpid() int {
+	return f.pid
+}
+
+// useFS replaces FS for the duration of a test
+func useFS(t *testing.T, f *fakeFS) {
+	t.Helper()
+	prev := FS
+	FS = f
+	t.Cleanup(func() { FS = prev })
+}
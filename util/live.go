@@ -15,22 +15,22 @@
 package util
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
 	"log/slog"
-	"os"
 )
 
 // IsLive checks is this process is running in a Live install
-func IsLive() bool {
-	var err error
-	if _, err = os.Stat("/run/initramfs/livedev"); err == nil {
+func IsLive() (bool, error) {
+	_, err := FS.Stat("/run/initramfs/livedev")
+	switch {
+	case err == nil:
 		slog.Debug("Live session detected")
-		return true
+		return true, nil
+	case errors.Is(err, fs.ErrNotExist):
+		return false, nil
+	default:
+		return false, fmt.Errorf("could not check for live session: %w", err)
 	}
-	if os.IsNotExist(err) {
-		return false
-	}
-	slog.Error("Could not check for live session", "reason", err)
-	// TODO: Return error instead of panicking.
-	panic("Could not check for live session")
-	//return false
 }
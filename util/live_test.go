@@ -0,0 +1,56 @@
+// Copyright © Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"io/fs"
+	"testing"
+)
+
+func TestIsLive(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]fakeFile
+		errs    map[string]error
+		want    bool
+		wantErr bool
+	}{
+		{
+			name:  "live medium",
+			files: map[string]fakeFile{"/run/initramfs/livedev": {data: "/dev/sr0"}},
+			want:  true,
+		},
+		{
+			name: "installed system",
+		},
+		{
+			name:    "inaccessible",
+			errs:    map[string]error{"/run/initramfs/livedev": fs.ErrPermission},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFS(t, &fakeFS{files: tt.files, errs: tt.errs})
+			got, err := IsLive()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("IsLive() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("IsLive() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}